@@ -0,0 +1,240 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Argument interface allows to match any argument in specific way
+// when used with WithArgs on query based expectations.
+type Argument interface {
+	Match(v any) bool
+}
+
+type anyArg struct{}
+
+func (anyArg) Match(any) bool {
+	return true
+}
+
+func (anyArg) String() string {
+	return "AnyArg()"
+}
+
+// AnyArg will return an Argument which can
+// match any kind of argument.
+func AnyArg() Argument {
+	return anyArg{}
+}
+
+type ofType struct {
+	typ reflect.Type
+}
+
+func (a ofType) Match(v any) bool {
+	return reflect.TypeOf(v) == a.typ
+}
+
+func (a ofType) String() string {
+	return fmt.Sprintf("OfType(%v)", a.typ)
+}
+
+// OfType will return an Argument which matches any
+// argument of the same Go type as the given example value,
+// e.g. OfType(uuid.UUID{}) or OfType(time.Time{}).
+func OfType(example any) Argument {
+	return ofType{typ: reflect.TypeOf(example)}
+}
+
+type regexpArg struct {
+	re *regexp.Regexp
+}
+
+func (a regexpArg) Match(v any) bool {
+	switch v := v.(type) {
+	case string:
+		return a.re.MatchString(v)
+	case []byte:
+		return a.re.Match(v)
+	case fmt.Stringer:
+		return a.re.MatchString(v.String())
+	}
+	return false
+}
+
+func (a regexpArg) String() string {
+	return fmt.Sprintf("Regexp(%s)", a.re)
+}
+
+// Regexp will return an Argument which matches string, []byte
+// and fmt.Stringer arguments against the given regular expression.
+// It panics if the expression cannot be parsed.
+func Regexp(expr string) Argument {
+	return regexpArg{re: regexp.MustCompile(expr)}
+}
+
+type timeWithin struct {
+	expected  time.Time
+	tolerance time.Duration
+}
+
+func (a timeWithin) Match(v any) bool {
+	var actual time.Time
+	switch v := v.(type) {
+	case time.Time:
+		actual = v
+	case *time.Time:
+		if v == nil {
+			return false
+		}
+		actual = *v
+	default:
+		return false
+	}
+	diff := actual.Sub(a.expected)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= a.tolerance
+}
+
+func (a timeWithin) String() string {
+	return fmt.Sprintf("TimeWithin(%s, %s)", a.expected.Format(time.RFC3339Nano), a.tolerance)
+}
+
+// TimeWithin will return an Argument which matches time.Time
+// arguments no further than tolerance away from expected.
+func TimeWithin(expected time.Time, tolerance time.Duration) Argument {
+	return timeWithin{expected: expected, tolerance: tolerance}
+}
+
+type floatNear struct {
+	expected  float64
+	tolerance float64
+}
+
+func (a floatNear) Match(v any) bool {
+	rv := reflect.ValueOf(v)
+	var actual float64
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		actual = rv.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = float64(rv.Uint())
+	default:
+		return false
+	}
+	return math.Abs(actual-a.expected) <= a.tolerance
+}
+
+func (a floatNear) String() string {
+	return fmt.Sprintf("FloatNear(%v, %v)", a.expected, a.tolerance)
+}
+
+// FloatNear will return an Argument which matches numeric
+// arguments no further than tolerance away from expected.
+func FloatNear(expected, tolerance float64) Argument {
+	return floatNear{expected: expected, tolerance: tolerance}
+}
+
+type sliceContains struct {
+	values []any
+}
+
+func (a sliceContains) Match(v any) bool {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return false
+	}
+	for _, expected := range a.values {
+		found := false
+		for i := 0; i < rv.Len(); i++ {
+			if matchArg(expected, rv.Index(i).Interface()) == nil {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (a sliceContains) String() string {
+	return fmt.Sprintf("SliceContains(%s)", joinValues(a.values))
+}
+
+// SliceContains will return an Argument which matches slice or array
+// arguments containing every one of the given values. Values may
+// themselves be Arguments.
+func SliceContains(values ...any) Argument {
+	return sliceContains{values: values}
+}
+
+type notArg struct {
+	value any
+}
+
+func (a notArg) Match(v any) bool {
+	return matchArg(a.value, v) != nil
+}
+
+func (a notArg) String() string {
+	return fmt.Sprintf("Not(%+v)", a.value)
+}
+
+// Not will return an Argument which matches any argument
+// the given value or Argument does not match.
+func Not(value any) Argument {
+	return notArg{value: value}
+}
+
+type anyOf struct {
+	values []any
+}
+
+func (a anyOf) Match(v any) bool {
+	for _, expected := range a.values {
+		if matchArg(expected, v) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (a anyOf) String() string {
+	return fmt.Sprintf("AnyOf(%s)", joinValues(a.values))
+}
+
+// AnyOf will return an Argument which matches an argument
+// satisfying at least one of the given values or Arguments.
+func AnyOf(values ...any) Argument {
+	return anyOf{values: values}
+}
+
+func joinValues(values []any) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		parts = append(parts, fmt.Sprintf("%+v", v))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,106 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArgumentMatchers(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	id := uuid.MustParse("0b3c1d9e-6a2f-4b8e-9f5d-3e2a1c0b9d8f")
+
+	tests := []struct {
+		name  string
+		arg   Argument
+		value any
+		match bool
+	}{
+		{"AnyArg", AnyArg(), "anything", true},
+		{"OfType", OfType(uuid.UUID{}), id, true},
+		{"OfType mismatch", OfType(""), 1, false},
+		{"Regexp string", Regexp(`^user-\d+$`), "user-42", true},
+		{"Regexp stringer", Regexp(`^[0-9a-f-]{36}$`), id, true},
+		{"Regexp mismatch", Regexp(`^user-\d+$`), "admin", false},
+		{"Regexp non string", Regexp(`.*`), 1, false},
+		{"TimeWithin", TimeWithin(now, time.Second), now.Add(500 * time.Millisecond), true},
+		{"TimeWithin before", TimeWithin(now, time.Second), now.Add(-500 * time.Millisecond), true},
+		{"TimeWithin outside", TimeWithin(now, time.Second), now.Add(2 * time.Second), false},
+		{"FloatNear", FloatNear(0.3, 1e-9), 0.1 + 0.2, true},
+		{"FloatNear float32", FloatNear(1.5, 0.01), float32(1.5), true},
+		{"FloatNear int", FloatNear(10, 0.5), uint64(10), true},
+		{"FloatNear outside", FloatNear(1, 0.1), 1.2, false},
+		{"SliceContains", SliceContains("a", "c"), []string{"a", "b", "c"}, true},
+		{"SliceContains matcher", SliceContains(Regexp("^b")), []string{"a", "bc"}, true},
+		{"SliceContains missing", SliceContains("d"), []string{"a", "b"}, false},
+		{"SliceContains non slice", SliceContains("a"), "a", false},
+		{"Not", Not(1), 2, true},
+		{"Not matcher", Not(AnyArg()), 2, false},
+		{"AnyOf", AnyOf(1, 2, 3), 2, true},
+		{"AnyOf mismatch", AnyOf(1, 2, 3), 4, false},
+		{"AnyOf nil", AnyOf(nil), 4, true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.match, tt.arg.Match(tt.value), tt.name)
+	}
+}
+
+func TestQueryWithArgumentMatchers(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery("SELECT avg(price) FROM items WHERE id = ? AND created_at > ?").
+		WithArgs(OfType(uuid.UUID{}), TimeWithin(time.Now(), time.Minute))
+	mock.ExpectQuery("SELECT avg(price) FROM items WHERE id = ? AND created_at > ?").
+		WithArgs(OfType(uuid.UUID{}), TimeWithin(time.Now(), time.Minute))
+
+	_, err = mock.Query(context.Background(), "SELECT avg(price) FROM items WHERE id = ? AND created_at > ?", uuid.New(), time.Now())
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when querying a statement", err)
+	}
+
+	_, err = mock.Query(context.Background(), "SELECT avg(price) FROM items WHERE id = ? AND created_at > ?", "not-a-uuid", time.Now())
+	if err == nil {
+		t.Error("an error was expected due to argument mismatch")
+	}
+}
+
+func TestQueryWithoutArgsIsStrict(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectExec("DELETE FROM items WHERE id = ?")
+	err = mock.Exec(context.Background(), "DELETE FROM items WHERE id = ?", 1)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "arguments do not match: expected 0 arguments, got 1")
+	}
+	assert.NoError(t, mock.Exec(context.Background(), "DELETE FROM items WHERE id = ?"))
+
+	ex := mock.ExpectExec("DELETE FROM items WHERE id = ?").WithAnyArgs()
+	assert.Contains(t, ex.String(), "is with any arguments")
+	assert.NoError(t, mock.Exec(context.Background(), "DELETE FROM items WHERE id = ?", 2))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,961 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The parser below covers the subset of ClickHouse SQL MatchStructural
+// compares structurally: SELECT statements, possibly combined with UNION,
+// EXCEPT or INTERSECT, INSERT and ALTER TABLE statements. Clauses it does
+// not understand in detail, like LIMIT or FORMAT, are kept as token lists.
+
+type nodeKind int
+
+const (
+	nodeSelect   nodeKind = iota // children are clauses
+	nodeSetOp                    // text is the operator, e.g. UNION ALL
+	nodeInsert                   // children are clauses
+	nodeAlter                    // children are clauses
+	nodeClause                   // text is the clause, e.g. WHERE
+	nodeSettings                 // children are settings
+	nodeSetting                  // text is the name, child is the value
+	nodeIdent
+	nodeLiteral
+	nodeParam // ?, $1 or {name:Type}
+	nodeStar
+	nodeAlias // text is the alias, child is the aliased expression
+	nodeAnd
+	nodeOr
+	nodeNot
+	nodeBinary  // text is the operator
+	nodeUnary   // text is the operator
+	nodeBetween // text is BETWEEN or NOT BETWEEN
+	nodeIsNull  // text is IS NULL or IS NOT NULL
+	nodeCall    // text is the function, children are argument lists
+	nodeList
+	nodeTuple
+	nodeArray
+	nodeSubquery
+	nodeCase
+	nodeLambda
+	nodeTernary
+	nodeIndex
+	nodeOrder // text is the direction and modifiers
+	nodeJoin  // text is the join kind, e.g. LEFT JOIN
+	nodeRaw   // text is the space separated tokens
+)
+
+type node struct {
+	kind     nodeKind
+	text     string
+	children []*node
+}
+
+func newNode(kind nodeKind, text string, children ...*node) *node {
+	return &node{kind: kind, text: text, children: children}
+}
+
+// parsedQuery is a statement parsed by parseQuery, along
+// with the position of each alias it defines.
+type parsedQuery struct {
+	root    *node
+	aliases map[string]int
+}
+
+type parseError struct {
+	msg string
+}
+
+// parser is a recursive descent parser over the tokens of a statement.
+// It panics with a parseError, recovered by parseQuery, on syntax it
+// does not support.
+type parser struct {
+	tokens  []token
+	pos     int
+	aliases map[string]int
+}
+
+// parseQuery parses a SELECT, INSERT or ALTER TABLE statement.
+func parseQuery(sql string) (q *parsedQuery, err error) {
+	p := &parser{tokens: normalize(tokenize(sql)), aliases: map[string]int{}}
+	defer func() {
+		if r := recover(); r != nil {
+			perr, ok := r.(parseError)
+			if !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("%s", perr.msg)
+		}
+	}()
+
+	var root *node
+	switch {
+	case p.isWord("SELECT", "WITH"), p.isOp("("):
+		root = p.parseSelect()
+	case p.isWord("INSERT"):
+		root = p.parseInsert()
+	case p.isWord("ALTER"):
+		root = p.parseAlter()
+	default:
+		p.fail("unsupported statement")
+	}
+	p.acceptOp(";")
+	if !p.eof() {
+		p.fail("unexpected %q", p.peek().text)
+	}
+	return &parsedQuery{root: root, aliases: p.aliases}, nil
+}
+
+func (p *parser) fail(format string, args ...any) {
+	panic(parseError{msg: fmt.Sprintf(format, args...)})
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	return p.peekAt(0)
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return token{kind: tokenComment}
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) next() token {
+	if p.eof() {
+		p.fail("unexpected end of statement")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t
+}
+
+// isWord tells whether the next token is any of the given words.
+func (p *parser) isWord(words ...string) bool {
+	return tokenIsWord(p.peek(), words...)
+}
+
+func tokenIsWord(t token, words ...string) bool {
+	if t.kind != tokenWord {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) isOp(ops ...string) bool {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) acceptWord(words ...string) bool {
+	if p.isWord(words...) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) acceptOp(op string) bool {
+	if p.isOp(op) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectWord(word string) {
+	if !p.acceptWord(word) {
+		p.fail("expected %s, got %q", word, p.peek().text)
+	}
+}
+
+func (p *parser) expectOp(op string) {
+	if !p.acceptOp(op) {
+		p.fail("expected %q, got %q", op, p.peek().text)
+	}
+}
+
+// selectClauses are the keywords starting a clause of a SELECT.
+var selectClauses = []string{
+	"WITH", "SELECT", "FROM", "ARRAY", "PREWHERE", "WHERE", "GROUP", "HAVING",
+	"WINDOW", "QUALIFY", "ORDER", "LIMIT", "OFFSET", "FETCH", "SETTINGS",
+	"FORMAT", "UNION", "EXCEPT", "INTERSECT", "INTO",
+}
+
+// joinWords are the keywords a join may start with.
+var joinWords = []string{
+	"JOIN", "GLOBAL", "LOCAL", "ANY", "ALL", "ASOF", "SEMI", "ANTI",
+	"INNER", "LEFT", "RIGHT", "FULL", "CROSS", "OUTER", "PASTE",
+}
+
+// stopWords end an expression, they are never taken as identifiers.
+var stopWords = append(append([]string{
+	"AND", "OR", "NOT", "AS", "ON", "USING", "FINAL", "SAMPLE", "BY", "ASC",
+	"DESC", "NULLS", "COLLATE", "THEN", "WHEN", "ELSE", "END", "IN", "LIKE",
+	"ILIKE", "BETWEEN", "IS", "VALUES", "DELETE", "UPDATE",
+}, selectClauses...), joinWords...)
+
+func (p *parser) parseSelect() *node {
+	s := p.parseSelectCore()
+	for p.isWord("UNION", "EXCEPT", "INTERSECT") {
+		op := strings.ToUpper(p.next().text)
+		if p.isWord("ALL", "DISTINCT") {
+			op += " " + strings.ToUpper(p.next().text)
+		}
+		s = newNode(nodeSetOp, op, s, p.parseSelectCore())
+	}
+	return s
+}
+
+func (p *parser) parseSelectCore() *node {
+	if p.acceptOp("(") {
+		s := p.parseSelect()
+		p.expectOp(")")
+		return s
+	}
+
+	s := newNode(nodeSelect, "")
+	if p.acceptWord("WITH") {
+		s.children = append(s.children, newNode(nodeClause, "WITH", p.parseAliasedList()...))
+	}
+	p.expectWord("SELECT")
+	clause := "SELECT"
+	if p.acceptWord("DISTINCT") {
+		clause += " DISTINCT"
+	}
+	s.children = append(s.children, newNode(nodeClause, clause, p.parseAliasedList()...))
+
+	for !p.eof() {
+		switch {
+		case p.acceptWord("FROM"):
+			s.children = append(s.children, newNode(nodeClause, "FROM", p.parseFrom()...))
+		case p.isWord("ARRAY") || p.isWord("LEFT") && tokenIsWord(p.peekAt(1), "ARRAY"):
+			clause := "ARRAY JOIN"
+			if p.acceptWord("LEFT") {
+				clause = "LEFT ARRAY JOIN"
+			}
+			p.expectWord("ARRAY")
+			p.expectWord("JOIN")
+			s.children = append(s.children, newNode(nodeClause, clause, p.parseAliasedList()...))
+		case p.isWord("PREWHERE", "WHERE", "HAVING", "QUALIFY"):
+			clause := strings.ToUpper(p.next().text)
+			s.children = append(s.children, newNode(nodeClause, clause, p.parseExpr()))
+		case p.acceptWord("GROUP"):
+			p.expectWord("BY")
+			s.children = append(s.children, newNode(nodeClause, "GROUP BY", p.parseList(p.parseExpr)...))
+			if p.isWord("WITH") {
+				s.children = append(s.children, p.parseRawClause(selectClauses...))
+			}
+		case p.acceptWord("ORDER"):
+			p.expectWord("BY")
+			s.children = append(s.children, newNode(nodeClause, "ORDER BY", p.parseList(p.parseOrderItem)...))
+		case p.isWord("SETTINGS"):
+			s.children = append(s.children, p.parseSettings())
+		case p.isWord("LIMIT", "OFFSET", "FETCH", "WINDOW", "FORMAT", "INTO"):
+			s.children = append(s.children, p.parseRawClause(selectClauses...))
+		default:
+			return s
+		}
+	}
+	return s
+}
+
+// parseFrom parses the table expression of a FROM clause and its joins.
+func (p *parser) parseFrom() []*node {
+	nodes := []*node{p.parseTableExpr()}
+	for p.isWord(joinWords...) || p.isOp(",") {
+		if p.acceptOp(",") {
+			nodes = append(nodes, newNode(nodeJoin, "CROSS JOIN", p.parseTableExpr()))
+			continue
+		}
+		if p.isWord("LEFT") && tokenIsWord(p.peekAt(1), "ARRAY") {
+			break
+		}
+		var kind []string
+		for !p.acceptWord("JOIN") {
+			if !p.isWord(joinWords...) {
+				p.fail("expected JOIN, got %q", p.peek().text)
+			}
+			kind = append(kind, strings.ToUpper(p.next().text))
+		}
+		kind = append(kind, "JOIN")
+		join := newNode(nodeJoin, strings.Join(kind, " "), p.parseTableExpr())
+		switch {
+		case p.acceptWord("ON"):
+			join.children = append(join.children, newNode(nodeClause, "ON", p.parseExpr()))
+		case p.acceptWord("USING"):
+			var using []*node
+			if p.acceptOp("(") {
+				using = p.parseList(p.parseExpr)
+				p.expectOp(")")
+			} else {
+				using = p.parseList(p.parseExpr)
+			}
+			join.children = append(join.children, newNode(nodeClause, "USING", using...))
+		}
+		nodes = append(nodes, join)
+	}
+	return nodes
+}
+
+// parseTableExpr parses a table, table function or subquery
+// with its alias and FINAL and SAMPLE modifiers.
+func (p *parser) parseTableExpr() *node {
+	n := p.parseAliased()
+	if p.acceptWord("FINAL") {
+		n = newNode(nodeClause, "FINAL", n)
+	}
+	if p.isWord("SAMPLE") {
+		n = newNode(nodeClause, "", n, p.parseRawClause(append(stopWords, "JOIN")...))
+	}
+	return n
+}
+
+func (p *parser) parseOrderItem() *node {
+	expr := p.parseExpr()
+	var modifiers []string
+	for p.isWord("ASC", "ASCENDING", "DESC", "DESCENDING", "NULLS", "FIRST", "LAST", "COLLATE") {
+		modifiers = append(modifiers, strings.ToUpper(p.next().text))
+		if modifiers[len(modifiers)-1] == "COLLATE" {
+			modifiers = append(modifiers, p.next().text)
+		}
+	}
+	direction := strings.Join(modifiers, " ")
+	direction = strings.NewReplacer("ASCENDING", "ASC", "DESCENDING", "DESC").Replace(direction)
+	if direction == "ASC" {
+		direction = ""
+	}
+	if p.isWord("WITH") && tokenIsWord(p.peekAt(1), "FILL") {
+		return newNode(nodeOrder, direction, expr, p.parseRawClause(append(selectClauses, ",")...))
+	}
+	return newNode(nodeOrder, direction, expr)
+}
+
+// parseSettings parses a SETTINGS clause.
+func (p *parser) parseSettings() *node {
+	p.expectWord("SETTINGS")
+	settings := newNode(nodeSettings, "SETTINGS")
+	for {
+		name := p.next()
+		if name.kind != tokenWord {
+			p.fail("expected setting name, got %q", name.text)
+		}
+		p.expectOp("=")
+		settings.children = append(settings.children, newNode(nodeSetting, name.text, p.parseExpr()))
+		if !p.acceptOp(",") {
+			return settings
+		}
+	}
+}
+
+// parseRawClause keeps the tokens up to any of the stop words,
+// or a closing parenthesis or semicolon, as they are.
+func (p *parser) parseRawClause(stop ...string) *node {
+	var texts []string
+	depth := 0
+	for !p.eof() {
+		t := p.peek()
+		if depth == 0 && len(texts) > 0 && (tokenIsWord(t, stop...) || t.kind == tokenOperator && contains(stop, t.text)) {
+			break
+		}
+		if t.kind == tokenOperator {
+			switch t.text {
+			case "(", "[":
+				depth++
+			case ")", "]":
+				if depth == 0 {
+					return newNode(nodeRaw, strings.Join(texts, " "))
+				}
+				depth--
+			case ";":
+				if depth == 0 {
+					return newNode(nodeRaw, strings.Join(texts, " "))
+				}
+			}
+		}
+		texts = append(texts, tokenText(p.next()))
+	}
+	return newNode(nodeRaw, strings.Join(texts, " "))
+}
+
+func (p *parser) parseInsert() *node {
+	p.expectWord("INSERT")
+	p.expectWord("INTO")
+	p.acceptWord("TABLE")
+	ins := newNode(nodeInsert, "")
+	if p.acceptWord("FUNCTION") {
+		ins.children = append(ins.children, newNode(nodeClause, "INSERT INTO FUNCTION", p.parsePrimary()))
+	} else {
+		ins.children = append(ins.children, newNode(nodeClause, "INSERT INTO", p.parseName()))
+	}
+	if p.isOp("(") {
+		p.next()
+		columns := newNode(nodeTuple, "")
+		if !p.isOp(")") {
+			columns.children = p.parseList(p.parseExpr)
+		}
+		p.expectOp(")")
+		ins.children = append(ins.children, newNode(nodeClause, "", columns))
+	}
+	if p.isWord("SETTINGS") {
+		ins.children = append(ins.children, p.parseSettings())
+	}
+	switch {
+	case p.acceptWord("VALUES"):
+		values := newNode(nodeClause, "VALUES")
+		// the VALUES clause of batch inserts is left empty
+		if !p.eof() && !p.isOp(";") {
+			values.children = p.parseList(p.parsePrimary)
+		}
+		ins.children = append(ins.children, values)
+	case p.isWord("SELECT", "WITH"), p.isOp("("):
+		ins.children = append(ins.children, p.parseSelect())
+	case p.isWord("FORMAT"):
+		ins.children = append(ins.children, p.parseRawClause())
+	}
+	return ins
+}
+
+func (p *parser) parseAlter() *node {
+	p.expectWord("ALTER")
+	p.expectWord("TABLE")
+	alter := newNode(nodeAlter, "", newNode(nodeClause, "ALTER TABLE", p.parseName()))
+	if p.isWord("ON") {
+		alter.children = append(alter.children, p.parseRawClause("ADD", "DROP", "MODIFY", "DELETE", "UPDATE", "RENAME", "CLEAR", "COMMENT", "MATERIALIZE", "ATTACH", "DETACH", "FREEZE", "REPLACE", "MOVE"))
+	}
+	for {
+		alter.children = append(alter.children, p.parseAlterCommand())
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+	if p.isWord("SETTINGS") {
+		alter.children = append(alter.children, p.parseSettings())
+	}
+	return alter
+}
+
+func (p *parser) parseAlterCommand() *node {
+	switch {
+	case p.acceptWord("DELETE"):
+		p.expectWord("WHERE")
+		return newNode(nodeClause, "DELETE WHERE", p.parseExpr())
+	case p.acceptWord("UPDATE"):
+		assignments := p.parseList(func() *node {
+			column := p.parseName()
+			p.expectOp("=")
+			return newNode(nodeBinary, "=", column, p.parseExpr())
+		})
+		cmd := newNode(nodeClause, "UPDATE", assignments...)
+		if p.acceptWord("IN") {
+			cmd.children = append(cmd.children, newNode(nodeClause, "IN PARTITION", p.parseRawClause("WHERE")))
+		}
+		p.expectWord("WHERE")
+		return newNode(nodeClause, "", cmd, newNode(nodeClause, "WHERE", p.parseExpr()))
+	}
+	return p.parseRawClause(",", "SETTINGS")
+}
+
+// parseName parses a possibly qualified table or column name.
+func (p *parser) parseName() *node {
+	t := p.next()
+	if t.kind != tokenWord && t.kind != tokenIdentifier {
+		p.fail("expected name, got %q", t.text)
+	}
+	name := identifierText(t)
+	for p.isOp(".") {
+		p.next()
+		name += "." + identifierText(p.next())
+	}
+	return newNode(nodeIdent, name)
+}
+
+// parseList parses a comma separated list of items.
+func (p *parser) parseList(item func() *node) []*node {
+	items := []*node{item()}
+	for p.acceptOp(",") {
+		items = append(items, item())
+	}
+	return items
+}
+
+func (p *parser) parseAliasedList() []*node {
+	return p.parseList(p.parseAliased)
+}
+
+// parseAliased parses an expression with an optional alias, which
+// is either introduced by AS or follows the expression directly.
+func (p *parser) parseAliased() *node {
+	expr := p.parseExpr()
+	if p.acceptWord("AS") {
+		if p.isOp("(") {
+			// WITH name AS (subquery)
+			return p.alias(expr.text, p.parsePrimary())
+		}
+		return p.alias(identifierText(p.next()), expr)
+	}
+	if t := p.peek(); t.kind == tokenIdentifier || t.kind == tokenWord && !isKeyword(t.text) && !tokenIsWord(t, stopWords...) {
+		return p.alias(identifierText(p.next()), expr)
+	}
+	return expr
+}
+
+func (p *parser) alias(name string, expr *node) *node {
+	if _, ok := p.aliases[name]; !ok {
+		p.aliases[name] = len(p.aliases)
+	}
+	return newNode(nodeAlias, name, expr)
+}
+
+// parseExpr parses an expression, from the operator
+// with the lowest precedence to the highest one.
+func (p *parser) parseExpr() *node {
+	expr := p.parseTernary()
+	if p.acceptOp("->") {
+		return newNode(nodeLambda, "->", expr, p.parseExpr())
+	}
+	return expr
+}
+
+func (p *parser) parseTernary() *node {
+	cond := p.parseOr()
+	if !p.acceptOp("?") {
+		return cond
+	}
+	then := p.parseTernary()
+	p.expectOp(":")
+	return newNode(nodeTernary, "?", cond, then, p.parseTernary())
+}
+
+func (p *parser) parseOr() *node {
+	return p.parseChain(nodeOr, "OR", p.parseAnd)
+}
+
+func (p *parser) parseAnd() *node {
+	return p.parseChain(nodeAnd, "AND", p.parseNot)
+}
+
+// parseChain parses operands joined by keyword into a single node,
+// so a AND b AND c is one node with three children.
+func (p *parser) parseChain(kind nodeKind, keyword string, operand func() *node) *node {
+	first := operand()
+	if !p.isWord(keyword) {
+		return first
+	}
+	chain := newNode(kind, keyword, first)
+	for p.acceptWord(keyword) {
+		chain.children = append(chain.children, operand())
+	}
+	return chain
+}
+
+func (p *parser) parseNot() *node {
+	if p.acceptWord("NOT") {
+		return newNode(nodeNot, "NOT", p.parseNot())
+	}
+	return p.parseComparison()
+}
+
+var comparisonOps = []string{"=", "==", "!=", "<>", "<", ">", "<=", ">=", "<=>"}
+
+func (p *parser) parseComparison() *node {
+	left := p.parseAdditive()
+	for {
+		switch {
+		case p.isOp(comparisonOps...):
+			op := p.next().text
+			if op == "==" {
+				op = "="
+			} else if op == "<>" {
+				op = "!="
+			}
+			left = newNode(nodeBinary, op, left, p.parseAdditive())
+		case p.isWord("IS"):
+			p.next()
+			op := "IS NULL"
+			if p.acceptWord("NOT") {
+				op = "IS NOT NULL"
+			}
+			p.expectWord("NULL")
+			left = newNode(nodeIsNull, op, left)
+		case p.isWord("BETWEEN") || p.isWord("NOT") && tokenIsWord(p.peekAt(1), "BETWEEN"):
+			op := "BETWEEN"
+			if p.acceptWord("NOT") {
+				op = "NOT BETWEEN"
+			}
+			p.expectWord("BETWEEN")
+			low := p.parseAdditive()
+			p.expectWord("AND")
+			left = newNode(nodeBetween, op, left, low, p.parseAdditive())
+		case p.isWord("IN", "LIKE", "ILIKE", "GLOBAL") ||
+			p.isWord("NOT") && tokenIsWord(p.peekAt(1), "IN", "LIKE", "ILIKE", "GLOBAL"):
+			var op []string
+			for !p.isWord("IN", "LIKE", "ILIKE") {
+				op = append(op, strings.ToUpper(p.next().text))
+			}
+			op = append(op, strings.ToUpper(p.next().text))
+			left = newNode(nodeBinary, strings.Join(op, " "), left, p.parseAdditive())
+		default:
+			return left
+		}
+	}
+}
+
+func (p *parser) parseAdditive() *node {
+	left := p.parseMultiplicative()
+	for p.isOp("+", "-", "||") {
+		op := p.next().text
+		left = newNode(nodeBinary, op, left, p.parseMultiplicative())
+	}
+	return left
+}
+
+func (p *parser) parseMultiplicative() *node {
+	left := p.parseUnary()
+	for p.isOp("*", "/", "%") || p.isWord("DIV", "MOD") {
+		op := strings.ToUpper(p.next().text)
+		left = newNode(nodeBinary, op, left, p.parseUnary())
+	}
+	return left
+}
+
+func (p *parser) parseUnary() *node {
+	if p.acceptOp("-") {
+		return newNode(nodeUnary, "-", p.parseUnary())
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() *node {
+	expr := p.parsePrimary()
+	for {
+		switch {
+		case p.acceptOp("["):
+			expr = newNode(nodeIndex, "[]", expr, p.parseExpr())
+			p.expectOp("]")
+		case p.acceptOp("::"):
+			expr = newNode(nodeCall, "CAST", newNode(nodeList, "", expr, p.parseType()))
+		case p.isOp(".") && p.peekAt(1).kind == tokenNumber:
+			p.next()
+			expr = newNode(nodeIndex, ".", expr, newNode(nodeLiteral, p.next().text))
+		default:
+			return expr
+		}
+	}
+}
+
+// parseType parses a ClickHouse type, e.g. Nullable(DateTime64(3, 'UTC')).
+func (p *parser) parseType() *node {
+	return newNode(nodeLiteral, p.parseRawClause(append(stopWords, ",", "=", "<", ">", "+", "-", "*", "/")...).text)
+}
+
+func (p *parser) parsePrimary() *node {
+	t := p.peek()
+	switch t.kind {
+	case tokenNumber, tokenString:
+		p.next()
+		return newNode(nodeLiteral, t.text)
+	case tokenIdentifier:
+		return p.parseIdentOrCall()
+	case tokenComment:
+		p.fail("unexpected end of statement")
+	case tokenOperator:
+		switch t.text {
+		case "?":
+			p.next()
+			return newNode(nodeParam, "?")
+		case "*":
+			p.next()
+			return newNode(nodeStar, "*")
+		case "{":
+			return p.parseParam()
+		case "[":
+			p.next()
+			arr := newNode(nodeArray, "")
+			if !p.isOp("]") {
+				arr.children = p.parseList(p.parseExpr)
+			}
+			p.expectOp("]")
+			return arr
+		case "(":
+			p.next()
+			if p.isWord("SELECT", "WITH") {
+				sub := newNode(nodeSubquery, "", p.parseSelect())
+				p.expectOp(")")
+				return sub
+			}
+			if p.acceptOp(")") {
+				return newNode(nodeTuple, "")
+			}
+			items := p.parseList(p.parseAliased)
+			p.expectOp(")")
+			if len(items) == 1 {
+				return items[0]
+			}
+			return newNode(nodeTuple, "", items...)
+		}
+		p.fail("unexpected %q", t.text)
+	}
+
+	switch {
+	case strings.HasPrefix(t.text, "$"):
+		p.next()
+		return newNode(nodeParam, t.text)
+	case p.isWord("NULL", "TRUE", "FALSE"):
+		p.next()
+		return newNode(nodeLiteral, strings.ToUpper(t.text))
+	case p.isWord("CASE"):
+		return p.parseCase()
+	case p.isWord("INTERVAL"):
+		p.next()
+		value := p.parseAdditive()
+		return newNode(nodeCall, "INTERVAL", newNode(nodeList, "", value, newNode(nodeLiteral, strings.ToUpper(p.next().text))))
+	case p.isWord("CAST") && p.peekAt(1).kind == tokenOperator && p.peekAt(1).text == "(":
+		p.next()
+		p.next()
+		value := p.parseExpr()
+		if !p.acceptWord("AS") {
+			p.expectOp(",")
+		}
+		typ := p.parseType()
+		p.expectOp(")")
+		typ.text = strings.Trim(typ.text, "' ")
+		return newNode(nodeCall, "CAST", newNode(nodeList, "", value, typ))
+	}
+	if !(p.peekAt(1).kind == tokenOperator && p.peekAt(1).text == "(") && p.isWord(stopWords...) {
+		p.fail("unexpected %s", t.text)
+	}
+	return p.parseIdentOrCall()
+}
+
+// parseParam parses a {name:Type} query parameter.
+func (p *parser) parseParam() *node {
+	p.expectOp("{")
+	text := "{"
+	for !p.acceptOp("}") {
+		text += p.next().text
+	}
+	return newNode(nodeParam, text+"}")
+}
+
+func (p *parser) parseCase() *node {
+	p.expectWord("CASE")
+	c := newNode(nodeCase, "CASE")
+	if !p.isWord("WHEN") {
+		c.children = append(c.children, newNode(nodeClause, "", p.parseExpr()))
+	}
+	for p.acceptWord("WHEN") {
+		cond := p.parseExpr()
+		p.expectWord("THEN")
+		c.children = append(c.children, newNode(nodeClause, "WHEN", cond, p.parseExpr()))
+	}
+	if p.acceptWord("ELSE") {
+		c.children = append(c.children, newNode(nodeClause, "ELSE", p.parseExpr()))
+	}
+	p.expectWord("END")
+	return c
+}
+
+// parseIdentOrCall parses a possibly qualified identifier,
+// or a function call if it is followed by arguments.
+func (p *parser) parseIdentOrCall() *node {
+	name := identifierText(p.next())
+	for p.isOp(".") {
+		p.next()
+		if p.acceptOp("*") {
+			return newNode(nodeStar, name+".*")
+		}
+		name += "." + identifierText(p.next())
+	}
+	if !p.isOp("(") {
+		return newNode(nodeIdent, name)
+	}
+
+	call := newNode(nodeCall, name)
+	for p.acceptOp("(") {
+		if p.isWord("SELECT", "WITH") {
+			// e.g. EXISTS(SELECT ...)
+			call.children = append(call.children, newNode(nodeList, "", p.parseSelect()))
+			p.expectOp(")")
+			continue
+		}
+		args := newNode(nodeList, "")
+		if p.acceptWord("DISTINCT") {
+			args.text = "DISTINCT"
+		}
+		if !p.isOp(")") {
+			args.children = p.parseList(p.parseAliased)
+		}
+		p.expectOp(")")
+		call.children = append(call.children, args)
+	}
+	if p.acceptWord("OVER") {
+		if p.acceptOp("(") {
+			call.children = append(call.children, newNode(nodeClause, "OVER", newNode(nodeTuple, "", p.parseRawClause())))
+			p.expectOp(")")
+		} else {
+			call.children = append(call.children, newNode(nodeClause, "OVER", newNode(nodeIdent, identifierText(p.next()))))
+		}
+	}
+	return call
+}
+
+// identifierText returns the name of an identifier, without its quotes.
+func identifierText(t token) string {
+	if t.kind == tokenIdentifier && len(t.text) >= 2 {
+		return t.text[1 : len(t.text)-1]
+	}
+	return t.text
+}
+
+// tokenText returns the text of a token, with keywords in upper case.
+func tokenText(t token) string {
+	if t.kind == tokenWord && isKeyword(t.text) {
+		return strings.ToUpper(t.text)
+	}
+	return identifierText(t)
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the node back to SQL, for mismatch descriptions.
+func (n *node) String() string {
+	switch n.kind {
+	case nodeSelect, nodeInsert, nodeAlter:
+		return joinNodes(n.children, " ")
+	case nodeSetOp:
+		return n.children[0].String() + " " + n.text + " " + n.children[1].String()
+	case nodeClause:
+		s := joinNodes(n.children, ", ")
+		if n.text == "" || n.text == "FROM" {
+			s = joinNodes(n.children, " ")
+		}
+		if n.text == "" {
+			return s
+		}
+		if n.text == "FINAL" {
+			return s + " FINAL"
+		}
+		if n.text == "WHEN" {
+			return "WHEN " + n.children[0].String() + " THEN " + n.children[1].String()
+		}
+		if len(n.children) == 0 {
+			return n.text
+		}
+		return n.text + " " + s
+	case nodeSettings:
+		return "SETTINGS " + joinNodes(n.children, ", ")
+	case nodeSetting:
+		return n.text + " = " + n.children[0].String()
+	case nodeAlias:
+		return n.children[0].String() + " AS " + n.text
+	case nodeAnd, nodeOr:
+		parts := make([]string, len(n.children))
+		for i, c := range n.children {
+			parts[i] = c.operand(n)
+		}
+		return strings.Join(parts, " "+n.text+" ")
+	case nodeNot:
+		return "NOT " + n.children[0].operand(n)
+	case nodeBinary:
+		return n.children[0].operand(n) + " " + n.text + " " + n.children[1].operand(n)
+	case nodeUnary:
+		return n.text + n.children[0].operand(n)
+	case nodeBetween:
+		return n.children[0].operand(n) + " " + n.text + " " + n.children[1].operand(n) + " AND " + n.children[2].operand(n)
+	case nodeIsNull:
+		return n.children[0].operand(n) + " " + n.text
+	case nodeCall:
+		var s strings.Builder
+		s.WriteString(n.text)
+		for _, c := range n.children {
+			if c.kind == nodeList {
+				s.WriteString("(" + strings.TrimSpace(c.text+" "+joinNodes(c.children, ", ")) + ")")
+			} else {
+				s.WriteString(" " + c.String())
+			}
+		}
+		return s.String()
+	case nodeList:
+		return joinNodes(n.children, ", ")
+	case nodeTuple:
+		return "(" + joinNodes(n.children, ", ") + ")"
+	case nodeArray:
+		return "[" + joinNodes(n.children, ", ") + "]"
+	case nodeSubquery:
+		return "(" + n.children[0].String() + ")"
+	case nodeCase:
+		return "CASE " + joinNodes(n.children, " ") + " END"
+	case nodeLambda:
+		return n.children[0].String() + " -> " + n.children[1].String()
+	case nodeTernary:
+		return n.children[0].String() + " ? " + n.children[1].String() + " : " + n.children[2].String()
+	case nodeIndex:
+		if n.text == "." {
+			return n.children[0].String() + "." + n.children[1].String()
+		}
+		return n.children[0].String() + "[" + n.children[1].String() + "]"
+	case nodeOrder:
+		return strings.TrimSpace(joinNodes(n.children, " ") + " " + n.text)
+	case nodeJoin:
+		return n.text + " " + joinNodes(n.children, " ")
+	}
+	return n.text
+}
+
+// operand renders n as an operand of parent,
+// in parentheses if it binds less tightly.
+func (n *node) operand(parent *node) string {
+	switch {
+	case n.kind == nodeOr && parent.kind != nodeOr,
+		n.kind == nodeAnd && parent.kind != nodeAnd && parent.kind != nodeOr,
+		n.kind == nodeBinary && parent.kind == nodeBinary:
+		return "(" + n.String() + ")"
+	}
+	return n.String()
+}
+
+func joinNodes(nodes []*node, sep string) string {
+	parts := make([]string, len(nodes))
+	for i, n := range nodes {
+		parts[i] = n.String()
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,80 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		query, parsed string
+	}{
+		{
+			"select id, count() c from db.articles as a final left join users u on u.id = a.author_id group by id order by c desc limit 10",
+			"SELECT id, count() AS c FROM db.articles AS a FINAL LEFT JOIN users AS u ON u.id = a.author_id GROUP BY id ORDER BY c DESC LIMIT 10",
+		},
+		{
+			"WITH 10 AS n SELECT quantile(0.9)(v), arrayMap(x -> x + 1, arr)[1], CAST(v AS UInt8), v::String, {id:UInt64}, $1 FROM t",
+			"WITH 10 AS n SELECT quantile(0.9)(v), arrayMap(x -> x + 1, arr)[1], CAST(v, UInt8), CAST(v, String), {id:UInt64}, $1 FROM t",
+		},
+		{
+			"SELECT * FROM t WHERE x BETWEEN 1 AND 2 AND y IS NOT NULL AND NOT z LIKE 'a%' AND w NOT IN (1, 2) UNION ALL SELECT * FROM t2",
+			"SELECT * FROM t WHERE x BETWEEN 1 AND 2 AND y IS NOT NULL AND NOT z LIKE 'a%' AND w NOT IN (1, 2) UNION ALL SELECT * FROM t2",
+		},
+		{
+			"SELECT CASE WHEN a THEN 1 ELSE 2 END, EXISTS(SELECT 1), count(DISTINCT x) OVER (PARTITION BY y) FROM t ARRAY JOIN arr AS el",
+			"SELECT CASE WHEN a THEN 1 ELSE 2 END, EXISTS(SELECT 1), count(DISTINCT x) OVER (PARTITION BY y) FROM t ARRAY JOIN arr AS el",
+		},
+		{
+			"INSERT INTO db.t (a, b) SETTINGS async_insert = 1 VALUES (1, 'a'), (2, 'b')",
+			"INSERT INTO db.t (a, b) SETTINGS async_insert = 1 VALUES (1, 'a'), (2, 'b')",
+		},
+		{
+			"INSERT INTO t SELECT * FROM s",
+			"INSERT INTO t SELECT * FROM s",
+		},
+		{
+			"insert into t (a, b) values",
+			"INSERT INTO t (a, b) VALUES",
+		},
+		{
+			"ALTER TABLE t ON CLUSTER c DELETE WHERE id = ?",
+			"ALTER TABLE t ON CLUSTER c DELETE WHERE id = ?",
+		},
+	} {
+		q, err := parseQuery(tc.query)
+		if assert.NoError(t, err, tc.query) {
+			assert.Equal(t, tc.parsed, q.root.String())
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	t.Parallel()
+	for _, query := range []string{
+		"TRUNCATE TABLE t",
+		"SELECT",
+		"SELECT id FROM",
+		"SELECT id FROM t WHERE",
+		"SELECT (id FROM t",
+		"SELECT id FROM t) x",
+		"INSERT t VALUES (1)",
+	} {
+		_, err := parseQuery(query)
+		assert.Error(t, err, query)
+	}
+}
@@ -0,0 +1,199 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// match checks the wait flag and, if set, the inserted values
+// in addition to what every query based expectation checks.
+func (e *ExpectedAsyncInsert) match(queryMatcher QueryMatcher, cl *call) error {
+	if err := e.queryBasedExpectation.match(queryMatcher, cl); err != nil {
+		return err
+	}
+	if cl.wait != e.expectWait {
+		return fmt.Errorf("'%s' was expected with wait %t, got %t", cl.query, e.expectWait, cl.wait)
+	}
+	if e.values == nil {
+		return nil
+	}
+	values, err := insertValues(cl.bound)
+	if err != nil {
+		return fmt.Errorf("'%s' values could not be read: %s", cl.query, err)
+	}
+	if err := matchValues(e.values, values); err != nil {
+		return fmt.Errorf("'%s' values do not match: %s", cl.query, err)
+	}
+	return nil
+}
+
+// WithValues expects the VALUES clause of the async INSERT, with the arguments
+// of the call rendered into it, to hold exactly the given rows. Values may be
+// Arguments, see Call.Values for the types the inserted values are read as.
+func (e *ExpectedAsyncInsert) WithValues(rows ...[]any) *ExpectedAsyncInsert {
+	e.values = rows
+	if e.values == nil {
+		e.values = [][]any{}
+	}
+	return e
+}
+
+// matchValues matches the inserted rows against the expected ones.
+func matchValues(expected, actual [][]any) error {
+	if len(expected) != len(actual) {
+		return fmt.Errorf("expected %d rows, got %d", len(expected), len(actual))
+	}
+	for i, row := range expected {
+		if len(row) != len(actual[i]) {
+			return fmt.Errorf("row %d: expected %d values, got %d", i, len(row), len(actual[i]))
+		}
+		for j, value := range row {
+			if err := matchArg(value, actual[i][j]); err != nil {
+				return fmt.Errorf("row %d: column %d: %s", i, j, err)
+			}
+		}
+	}
+	return nil
+}
+
+// insertValues reads the rows of the VALUES clause of an INSERT statement.
+func insertValues(sql string) ([][]any, error) {
+	q, err := parseQuery(sql)
+	if err != nil {
+		return nil, err
+	}
+	if q.root.kind != nodeInsert {
+		return nil, errors.New("not an INSERT statement")
+	}
+	for _, clause := range q.root.children {
+		if clause.kind != nodeClause || clause.text != "VALUES" {
+			continue
+		}
+		rows := make([][]any, 0, len(clause.children))
+		for _, n := range clause.children {
+			// the parser takes a row of one value for the value itself
+			if n.kind != nodeTuple {
+				n = newNode(nodeTuple, "", n)
+			}
+			row := make([]any, 0, len(n.children))
+			for _, v := range n.children {
+				value, err := literalValue(v)
+				if err != nil {
+					return nil, err
+				}
+				row = append(row, value)
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+	return nil, errors.New("the INSERT has no VALUES clause")
+}
+
+// literalValue converts a literal to the Go value it stands for: nil for
+// NULL, a bool, a string, an int64, uint64 or float64 for numbers and an
+// []any for arrays and tuples. Other expressions are given as their SQL.
+func literalValue(n *node) (any, error) {
+	switch n.kind {
+	case nodeLiteral:
+		switch {
+		case n.text == "NULL":
+			return nil, nil
+		case n.text == "TRUE":
+			return true, nil
+		case n.text == "FALSE":
+			return false, nil
+		case strings.HasPrefix(n.text, "'"):
+			return unquote(n.text)
+		}
+		if v, ok := numberValue(n.text); ok {
+			return v, nil
+		}
+	case nodeUnary:
+		if n.text == "-" && n.children[0].kind == nodeLiteral {
+			if v, ok := numberValue("-" + n.children[0].text); ok {
+				return v, nil
+			}
+		}
+	case nodeArray, nodeTuple:
+		values := make([]any, 0, len(n.children))
+		for _, c := range n.children {
+			value, err := literalValue(c)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	return n.String(), nil
+}
+
+func numberValue(text string) (any, bool) {
+	if v, err := strconv.ParseInt(text, 0, 64); err == nil {
+		return v, true
+	}
+	if v, err := strconv.ParseUint(text, 0, 64); err == nil {
+		return v, true
+	}
+	if v, err := strconv.ParseFloat(text, 64); err == nil {
+		return v, true
+	}
+	return nil, false
+}
+
+// unquote returns the value of a single quoted string literal,
+// undoing doubled quotes and backslash escapes.
+func unquote(literal string) (string, error) {
+	if len(literal) < 2 || literal[len(literal)-1] != '\'' {
+		return "", fmt.Errorf("unterminated string literal %s", literal)
+	}
+	s := literal[1 : len(literal)-1]
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '0':
+				b.WriteByte(0)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'a':
+				b.WriteByte('\a')
+			case 'v':
+				b.WriteByte('\v')
+			default:
+				b.WriteByte(s[i])
+			}
+		case s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+			b.WriteByte('\'')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,135 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncInsertWait(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectAsyncInsert("INSERT INTO events VALUES (?)", true).WithArgs("click")
+	err = mock.AsyncInsert(context.Background(), "INSERT INTO events VALUES (?)", false, "click")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "'INSERT INTO events VALUES (?)' was expected with wait true, got false")
+	}
+	err = mock.AsyncInsert(context.Background(), "INSERT INTO events VALUES (?)", true, "view")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "arguments do not match")
+	}
+	assert.NoError(t, mock.AsyncInsert(context.Background(), "INSERT INTO events VALUES (?)", true, "click"))
+
+	mock.ExpectAsyncInsert("INSERT INTO events VALUES ('view')", false).WillWait()
+	assert.NoError(t, mock.AsyncInsert(context.Background(), "INSERT INTO events VALUES ('view')", true))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAsyncInsertContextOptions(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectAsyncInsert("INSERT INTO events VALUES ('click')", true).
+		WithSettings(clickhouse.Settings{"async_insert": 1, "wait_for_async_insert": 1})
+	mock.ExpectExec("INSERT INTO events VALUES ('view')")
+
+	ctx := clickhouse.Context(context.Background(), clickhouse.WithAsync(true))
+	assert.NoError(t, mock.Exec(ctx, "INSERT INTO events VALUES ('click')"))
+	assert.NoError(t, mock.Exec(ctx, "INSERT INTO events VALUES ('view')"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectAsyncInsert("INSERT INTO events VALUES ('click')", true)
+	ctx = clickhouse.Context(context.Background(), clickhouse.WithStdAsync(false))
+	err = mock.Exec(ctx, "INSERT INTO events VALUES ('click')")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "was expected with wait true, got false")
+	}
+
+	calls := mock.Calls()
+	if assert.Len(t, calls, 3) {
+		assert.True(t, calls[0].Async)
+		assert.True(t, calls[0].Wait)
+		assert.Equal(t, clickhouse.Settings{"async_insert": 1, "wait_for_async_insert": 1}, calls[0].Settings)
+		assert.False(t, calls[2].Wait)
+	}
+}
+
+func TestAsyncInsertValues(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	query := "INSERT INTO events (id, name, tags, score, at) VALUES (?, ?, ?, ?, now()), (2, 'it''s', [], -1.5, NULL)"
+	mock.ExpectAsyncInsert(query, false).
+		WithAnyArgs().
+		WithValues(
+			[]any{int64(1), "o'clock", []any{"a", "b"}, int64(-3), "now()"},
+			[]any{int64(2), "it's", []any{}, -1.5, nil},
+		)
+	mock.ExpectAsyncInsert("INSERT INTO events (id) VALUES (?)", false).
+		WithAnyArgs().
+		WithValues([]any{AnyArg()}, []any{int64(2)})
+
+	assert.NoError(t, mock.AsyncInsert(context.Background(), query, false, 1, "o'clock", []string{"a", "b"}, -3))
+	err = mock.AsyncInsert(context.Background(), "INSERT INTO events (id) VALUES (?)", false, 1)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "values do not match: expected 2 rows, got 1")
+	}
+
+	calls := mock.Calls()
+	if assert.Len(t, calls, 2) {
+		assert.Equal(t, [][]any{
+			{int64(1), "o'clock", []any{"a", "b"}, int64(-3), "now()"},
+			{int64(2), "it's", []any{}, -1.5, nil},
+		}, calls[0].Values)
+		assert.Equal(t, [][]any{{int64(1)}}, calls[1].Values)
+	}
+}
+
+func TestAsyncInsertMalformedValues(t *testing.T) {
+	t.Parallel()
+	for _, query := range []string{
+		"INSERT INTO events VALUES '",
+		"INSERT INTO events VALUES ('click)",
+	} {
+		_, err := insertValues(query)
+		assert.Error(t, err, query)
+	}
+
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.ExpectAsyncInsert("INSERT INTO events VALUES '", false).WithValues([]any{"click"})
+	err = mock.AsyncInsert(context.Background(), "INSERT INTO events VALUES '", false)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "values could not be read: unterminated string literal '")
+	}
+	calls := mock.Calls()
+	if assert.Len(t, calls, 1) {
+		assert.Nil(t, calls[0].Values)
+	}
+}
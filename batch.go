@@ -13,74 +13,521 @@
 package mockhouse
 
 import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
 )
 
 var _ driver.Batch = (*batch)(nil)
 
 type batch struct {
-	conn  *clickhousemock
-	ex    *ExpectedPrepareBatch
-	query string
+	conn      *clickhousemock
+	ex        *ExpectedPrepareBatch
+	query     string
+	ctx       context.Context
+	rows      [][]any
+	structMap *structMap
+	// names are the columns of the INSERT column list, or of the
+	// expectation if it has columns and the INSERT has no column list
+	names []string
+	// block holds the appended values if the expectation has columns,
+	// converting them like clickhouse-go does
+	block *proto.Block
+	// err is set by a failed append, which invalidates the batch
+	err error
+	// columns are the values appended through Column
+	columns [][]any
+	// flushed is the number of rows sent with Flush
+	flushed int
+	// sent is set by Send, Abort and Close like in clickhouse-go
+	sent bool
+}
+
+// usable returns the error the driver returns for a batch which
+// was sent, aborted or invalidated by a failed append.
+func (b *batch) usable() error {
+	if b.sent {
+		return clickhouse.ErrBatchAlreadySent
+	}
+	return b.err
+}
+
+// newBatchBlock builds the block of a batch prepared for the given query
+// from the columns of ex, picking the ones of the INSERT column list if
+// it has one. It returns nil if ex has no columns.
+func newBatchBlock(ex *ExpectedPrepareBatch, query string) (*proto.Block, []string, error) {
+	names := insertColumns(query)
+	if ex.columns == nil {
+		return nil, names, nil
+	}
+	columns := ex.columns
+	if names != nil {
+		columns = make([]ColumnType, 0, len(names))
+	next:
+		for _, name := range names {
+			for _, col := range ex.columns {
+				if col.Name == name {
+					columns = append(columns, col)
+					continue next
+				}
+			}
+			return nil, nil, &OpError{
+				Op:  "PrepareBatch",
+				Err: fmt.Errorf("no such column %q in the columns of the expectation", name),
+			}
+		}
+	}
+	block, err := newBlock(columns, ex.timezone)
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, block.ColumnsNames(), nil
+}
+
+// invalidate records the failed append of err, which makes the
+// batch unusable like in clickhouse-go, and returns err.
+func (b *batch) invalidate(err error) error {
+	b.err = fmt.Errorf("%w: %w", clickhouse.ErrBatchInvalid, err)
+	return err
+}
+
+// appendRow appends a row to the batch, through its block if it has one.
+func (b *batch) appendRow(v []any) error {
+	if b.block != nil {
+		if err := b.block.Append(v...); err != nil {
+			return b.invalidate(err)
+		}
+	}
+	b.rows = append(b.rows, v)
+	b.ex.Lock()
+	b.ex.appended = append(b.ex.appended, v)
+	b.ex.Unlock()
+	b.appendedRows()
+	return nil
+}
+
+// count returns the number of rows appended to the batch. Like in
+// clickhouse-go, the values appended through Column count by the
+// first column.
+func (b *batch) count() int {
+	n := len(b.rows)
+	if len(b.columns) != 0 {
+		n += len(b.columns[0])
+	}
+	return n
+}
+
+// appendedRows records the number of rows appended so far
+// on the expectation, for ExpectationsWereMet to check.
+func (b *batch) appendedRows() {
+	b.ex.Lock()
+	b.ex.appendedRows = b.count()
+	b.ex.Unlock()
 }
 
 type batchcolumn struct {
-	conn  *clickhousemock
-	ex    *ExpectedPrepareBatch
-	query string
+	batch *batch
+	index int
+	err   error
+	// column is the column of the block of the batch, if it has one
+	column column.Interface
+}
+
+// Append appends a slice of values to the column.
+func (b *batchcolumn) Append(v any) error {
+	if b.err != nil {
+		return b.err
+	}
+	if err := b.batch.usable(); err != nil {
+		return err
+	}
+	if b.batch.ex.appendErr != nil {
+		return b.batch.ex.appendErr
+	}
+	values := reflect.ValueOf(v)
+	if k := values.Kind(); k != reflect.Slice && k != reflect.Array {
+		return b.batch.invalidate(&OpError{
+			Op:         "batch.Column.Append",
+			ColumnName: b.batch.columnName(b.index),
+			Err:        fmt.Errorf("expected a slice of values, got %T", v),
+		})
+	}
+	if b.column != nil {
+		if _, err := b.column.Append(v); err != nil {
+			return b.batch.invalidate(err)
+		}
+	}
+	for i := 0; i < values.Len(); i++ {
+		b.batch.columns[b.index] = append(b.batch.columns[b.index], values.Index(i).Interface())
+	}
+	b.batch.appendedRows()
+	return nil
 }
 
-func (b batchcolumn) Append(any) error {
-	return b.ex.appendErr
+// AppendRow appends a single value to the column.
+func (b *batchcolumn) AppendRow(v any) error {
+	if b.err != nil {
+		return b.err
+	}
+	if err := b.batch.usable(); err != nil {
+		return err
+	}
+	if b.batch.ex.appendErr != nil {
+		return b.batch.ex.appendErr
+	}
+	if b.column != nil {
+		if err := b.column.AppendRow(v); err != nil {
+			return b.batch.invalidate(err)
+		}
+	}
+	b.batch.columns[b.index] = append(b.batch.columns[b.index], v)
+	b.batch.appendedRows()
+	return nil
 }
 
-func (b batchcolumn) AppendRow(any) error {
-	return b.ex.appendErr
+// columnName returns the name of the i-th column,
+// or its index if the INSERT has no column list.
+func (b *batch) columnName(i int) string {
+	if i < len(b.names) {
+		return b.names[i]
+	}
+	return strconv.Itoa(i)
 }
 
-func (b *batch) Abort() error {
-	return b.ex.abortErr
+// checkColumns checks the columns appended through Column all have as
+// many values, as the driver does before sending the block, and that
+// the values match the ones expected with ExpectColumn.
+func (b *batch) checkColumns() error {
+	if b.block != nil && len(b.block.Columns) != 0 {
+		for _, col := range b.block.Columns[1:] {
+			if rows := b.block.Columns[0].Rows(); col.Rows() != rows {
+				return &proto.BlockError{
+					Op:  "Encode",
+					Err: fmt.Errorf("mismatched len of columns - expected %d, received %d for col %s", rows, col.Rows(), col.Name()),
+				}
+			}
+		}
+	}
+	if len(b.columns) == 0 {
+		return nil
+	}
+	for i := 1; i < len(b.columns); i++ {
+		if len(b.columns[i]) != len(b.columns[0]) {
+			return &proto.BlockError{
+				Op: "Encode",
+				Err: fmt.Errorf("mismatched len of columns - expected %d, received %d for col %s",
+					len(b.columns[0]), len(b.columns[i]), b.columnName(i)),
+			}
+		}
+	}
+	for _, ex := range b.ex.steps() {
+		col, ok := ex.(*ExpectedColumn)
+		if !ok || col.values == nil || col.index >= len(b.columns) {
+			continue
+		}
+		if err := col.matchValues(b.columns[col.index]); err != nil {
+			err = fmt.Errorf("Send: column %d values do not match: %v, expectation is: %s", col.index, err, col)
+			b.conn.unexpected(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// appended returns the rows appended to the batch, the ones
+// appended through Column following the ones appended with Append.
+func (b *batch) appended() [][]any {
+	if len(b.columns) == 0 {
+		return b.rows
+	}
+	rows := append([][]any{}, b.rows...)
+	for r := range b.columns[0] {
+		row := make([]any, len(b.columns))
+		for i := range b.columns {
+			row[i] = b.columns[i][r]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// insertColumns returns the names in the column list of an INSERT
+// statement, or nil if the statement has none.
+func insertColumns(sql string) []string {
+	q, err := parseQuery(sql)
+	if err != nil || q.root.kind != nodeInsert {
+		return nil
+	}
+	for _, clause := range q.root.children {
+		if clause.kind != nodeClause || clause.text != "" || len(clause.children) != 1 || clause.children[0].kind != nodeTuple {
+			continue
+		}
+		names := make([]string, 0, len(clause.children[0].children))
+		for _, n := range clause.children[0].children {
+			if n.kind == nodeIdent {
+				names = append(names, n.text)
+			} else {
+				names = append(names, n.String())
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// call starts a call of a batch method, recorded once it returns
+// with b.conn.record.
+func (b *batch) call(method string, args []any) *call {
+	cl := newCall(b.ctx, method, b.query, args)
+	cl.batch = true
+	return cl
 }
 
-func (b *batch) Append(v ...any) error {
-	for _, ex := range b.ex.expected {
-		if ap, ok := ex.(*ExpectedAppend); ok && !ap.triggered {
-			ap.triggered = true
-			break
+// trigger records the call of a batch method on the first expectation
+// for it which may still be matched, or, unless expectations are matched
+// in order, on the first such expectation the call matches. If the batch
+// has expectations and the call matches none of them, the error is
+// reported and returned. A batch without expectations accepts any call.
+func (b *batch) trigger(cl *call) error {
+	b.conn.expectedMu.Lock()
+	defer b.conn.expectedMu.Unlock()
+
+	steps := b.ex.steps()
+	var first error
+	for _, ex := range steps {
+		ex.Lock()
+		if ex.method() != cl.method || ex.exhausted() {
+			ex.Unlock()
+			continue
 		}
+		if err := ex.match(b.conn.queryMatcher, cl); err != nil {
+			ex.Unlock()
+			if first == nil {
+				first = fmt.Errorf("%s: %v, expectation is: %s", cl.method, err, ex)
+			}
+			if b.conn.ordered {
+				break
+			}
+			continue
+		}
+		ex.common().trigger()
+		cl.ex = ex
+		ex.Unlock()
+		return nil
+	}
+	if first == nil && len(steps) != 0 {
+		first = fmt.Errorf("call to batch %s was not expected", cl)
 	}
-	return b.ex.appendErr
+	if first != nil {
+		b.conn.unexpected(first)
+	}
+	return first
 }
 
-func (b *batch) AppendStruct(v any) error {
-	return b.ex.appendStructErr
+func (b *batch) Abort() (err error) {
+	cl := b.call("Abort", nil)
+	defer b.conn.record(cl, &err)
+
+	if b.sent {
+		return clickhouse.ErrBatchAlreadySent
+	}
+	if err := b.trigger(cl); err != nil {
+		return err
+	}
+	b.sent = true
+	b.ex.Lock()
+	b.ex.wasAborted = true
+	b.ex.Unlock()
+	return b.ex.abortErr
 }
 
-func (b *batch) Column(int) driver.BatchColumn {
-	return batchcolumn{conn: b.conn, ex: b.ex, query: b.query}
+func (b *batch) Append(v ...any) (err error) {
+	cl := b.call("Append", v)
+	defer b.conn.record(cl, &err)
+
+	if err := b.usable(); err != nil {
+		return err
+	}
+	if err := b.trigger(cl); err != nil {
+		return err
+	}
+	if b.ex.appendErr != nil {
+		return b.ex.appendErr
+	}
+	return b.appendRow(v)
+}
+
+// AppendStruct appends the fields of the struct v points to as a row,
+// mapped to the columns of the INSERT column list, or to the ones set with
+// WithColumns, like clickhouse-go maps them to the columns of the table.
+// If neither is known, the struct itself is stored as the row.
+func (b *batch) AppendStruct(v any) (err error) {
+	cl := b.call("AppendStruct", []any{v})
+	defer b.conn.record(cl, &err)
+
+	if err := b.usable(); err != nil {
+		return err
+	}
+	values := []any{v}
+	if b.names != nil {
+		if values, err = b.structMap.Map("AppendStruct", b.names, v, false); err != nil {
+			return err
+		}
+	}
+	if err := b.trigger(cl); err != nil {
+		return err
+	}
+	if b.ex.appendStructErr != nil {
+		return b.ex.appendStructErr
+	}
+	return b.appendRow(values)
+}
+
+func (b *batch) Column(i int) driver.BatchColumn {
+	var err error
+	cl := b.call("Column", []any{i})
+	defer b.conn.record(cl, &err)
+
+	if err = b.usable(); err != nil {
+		return &batchcolumn{err: err}
+	}
+	if i < 0 || b.names != nil && i >= len(b.names) {
+		err = &OpError{
+			Op:  "batch.Column",
+			Err: fmt.Errorf("invalid column index %d", i),
+		}
+		return &batchcolumn{err: err}
+	}
+	if err = b.trigger(cl); err != nil {
+		return &batchcolumn{err: err}
+	}
+	if col, ok := cl.ex.(*ExpectedColumn); ok && col.batchCoulmn != nil {
+		return col.batchCoulmn
+	}
+	for len(b.columns) <= i {
+		b.columns = append(b.columns, []any{})
+	}
+	// columns left out of the column list still have to be filled
+	for len(b.columns) < len(b.names) {
+		b.columns = append(b.columns, []any{})
+	}
+	col := &batchcolumn{batch: b, index: i}
+	if b.block != nil {
+		col.column = b.block.Columns[i]
+	}
+	return col
 }
 
-func (b *batch) Flush() error {
-	return b.ex.flushErr
+func (b *batch) Flush() (err error) {
+	cl := b.call("Flush", nil)
+	defer b.conn.record(cl, &err)
+
+	if err := b.usable(); err != nil {
+		return err
+	}
+	if err := b.trigger(cl); err != nil {
+		return err
+	}
+	if b.ex.flushErr != nil {
+		return b.ex.flushErr
+	}
+	b.flushed = b.count()
+	if b.block != nil {
+		b.block.Reset()
+	}
+	return nil
 }
 
-func (b *batch) Send() error {
-	return b.ex.sendErr
+// Send sends the batch. Like in clickhouse-go, the batch counts as
+// sent afterwards even if Send failed, so it may not be sent again.
+func (b *batch) Send() (err error) {
+	cl := b.call("Send", nil)
+	defer b.conn.record(cl, &err)
+
+	defer func() {
+		b.sent = true
+	}()
+	if err := b.usable(); err != nil {
+		return err
+	}
+	if err := b.trigger(cl); err != nil {
+		return err
+	}
+	if b.ex.sendErr != nil {
+		return b.ex.sendErr
+	}
+	if err := b.checkColumns(); err != nil {
+		return err
+	}
+	b.ex.Lock()
+	b.ex.wasSent = true
+	b.ex.Unlock()
+	b.ex.events.emit(b.ctx)
+	if b.ex.respond != nil {
+		return b.ex.respond(b.ctx, b.query, b.appended())
+	}
+	return nil
 }
 
 func (b *batch) IsSent() bool {
-	return b.ex.mustBeSent
+	var err error
+	cl := b.call("IsSent", nil)
+	defer b.conn.record(cl, &err)
+
+	b.trigger(cl)
+	if ex, ok := cl.ex.(*ExpectedIsSent); ok && ex.isSent != nil {
+		return *ex.isSent
+	}
+	return b.sent
 }
 
+// Rows returns the number of rows appended since the batch was prepared
+// or last flushed.
 func (b *batch) Rows() int {
-	return b.ex.rows
+	var err error
+	defer b.conn.record(b.call("Rows", nil), &err)
+
+	b.ex.Lock()
+	rows := b.ex.rows
+	b.ex.Unlock()
+	if rows != nil {
+		return *rows
+	}
+	return b.count() - b.flushed
 }
 
+// Columns returns the columns of the block of the batch,
+// or nil if its expectation has no columns.
 func (b *batch) Columns() []column.Interface {
-	return nil
+	var err error
+	defer b.conn.record(b.call("Columns", nil), &err)
+
+	if b.block == nil {
+		return nil
+	}
+	return slices.Clone(b.block.Columns)
 }
 
-func (b *batch) Close() error {
+// Close ends the batch without sending it. Like in clickhouse-go,
+// it does nothing if the batch was sent or aborted.
+func (b *batch) Close() (err error) {
+	cl := b.call("Close", nil)
+	defer b.conn.record(cl, &err)
+
+	b.trigger(cl)
+	b.ex.Lock()
+	b.ex.wasClosed = true
+	b.ex.Unlock()
+	if b.sent {
+		return nil
+	}
+	b.sent = true
 	return b.ex.closeErr
 }
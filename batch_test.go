@@ -0,0 +1,413 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchAppendWithArgs(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	prep := mock.ExpectPrepareBatch("INSERT INTO events")
+	prep.ExpectAppend().WithArgs(uint32(1), "click")
+	prep.ExpectAppend().WithArgs(AnyArg(), Regexp("^vi"))
+	prep.ExpectSend()
+
+	batch, err := mock.PrepareBatch(context.Background(), "INSERT INTO events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, batch.Append(uint32(1), "click"))
+
+	err = batch.Append(uint32(2), "scroll")
+	if assert.Error(t, err) {
+		assert.Equal(t, "Append: appended row does not match: argument 1: argument Regexp(^vi) does not match scroll (string), "+
+			"expectation is: Append(INSERT INTO events) with arguments AnyArg(), Regexp(^vi)", err.Error())
+	}
+	err = batch.Append(uint32(2))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "appended row does not match: expected 2 arguments, got 1")
+	}
+	assert.NoError(t, batch.Append(uint32(2), "view"))
+	err = batch.Append("garbage", 99)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "call to batch Append")
+		assert.Contains(t, err.Error(), "was not expected")
+	}
+	err = batch.Flush()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "call to batch Flush")
+	}
+	assert.NoError(t, batch.Send())
+
+	assert.Equal(t, [][]any{{uint32(1), "click"}, {uint32(2), "view"}}, prep.AppendedRows())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchColumns(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	var sent [][]any
+	prep := mock.ExpectPrepareBatch("INSERT INTO events (id, name)").
+		WillRespond(func(_ context.Context, _ string, rows [][]any) error {
+			sent = rows
+			return nil
+		})
+	prep.ExpectColumn(0).WithValues(uint32(1), uint32(2))
+	prep.ExpectColumn(1).WithValues("click", AnyArg())
+	prep.ExpectSend()
+
+	batch, err := mock.PrepareBatch(context.Background(), "INSERT INTO events (id, name)")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, batch.Column(0).Append([]uint32{1, 2}))
+	names := batch.Column(1)
+	assert.NoError(t, names.AppendRow("click"))
+	assert.NoError(t, names.AppendRow("view"))
+	assert.NoError(t, batch.Send())
+
+	assert.Equal(t, [][]any{{uint32(1), "click"}, {uint32(2), "view"}}, sent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchColumnErrors(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	columnPrep := mock.ExpectPrepareBatch("INSERT INTO events (id, name)")
+	columnPrep.ExpectColumn(0).WithValues(uint32(1))
+	columnPrep.ExpectSend()
+	batch, err := mock.PrepareBatch(context.Background(), "INSERT INTO events (id, name)")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	err = batch.Column(2).Append([]uint32{1})
+	assert.EqualError(t, err, "clickhouse [batch.Column]: invalid column index 2")
+	err = batch.Column(1).Append("click")
+	assert.EqualError(t, err, "Column: expected column 0, got 1, expectation is: Column(INSERT INTO events (id, name), 0)")
+
+	assert.NoError(t, batch.Column(0).Append([]uint32{1}))
+	err = batch.Send()
+	assert.EqualError(t, err, "clickhouse [Encode]:  mismatched len of columns - expected 1, received 0 for col name")
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	prep := mock.ExpectPrepareBatch("INSERT INTO events (id)")
+	prep.ExpectColumn(0)
+	batch, err = mock.PrepareBatch(context.Background(), "INSERT INTO events (id)")
+	if !assert.NoError(t, err) {
+		return
+	}
+	col := batch.Column(0)
+	err = col.Append(uint32(1))
+	assert.EqualError(t, err, "clickhouse [batch.Column.Append]: expected a slice of values, got uint32")
+	assert.ErrorIs(t, col.AppendRow(uint32(1)), clickhouse.ErrBatchInvalid)
+	assert.ErrorIs(t, batch.Column(0).Append([]uint32{1}), clickhouse.ErrBatchInvalid)
+	assert.Empty(t, prep.AppendedRows())
+
+	mock.ExpectPrepareBatch("INSERT INTO events (id)").ExpectSend()
+	batch, err = mock.PrepareBatch(context.Background(), "INSERT INTO events (id)")
+	if assert.NoError(t, err) {
+		assert.NoError(t, batch.Send())
+		assert.ErrorIs(t, batch.Column(0).Append([]uint32{1}), clickhouse.ErrBatchAlreadySent)
+	}
+}
+
+func TestBatchColumnValuesMismatch(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.MatchExpectationsInOrder(false)
+	prep := mock.ExpectPrepareBatch("INSERT INTO events")
+	prep.ExpectColumn(0).WithValues(uint32(1))
+	prep.ExpectColumn(1).WithValues("click")
+	prep.ExpectSend()
+	batch, err := mock.PrepareBatch(context.Background(), "INSERT INTO events")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.NoError(t, batch.Column(1).AppendRow("view"))
+	assert.NoError(t, batch.Column(0).AppendRow(uint32(1)))
+	err = batch.Send()
+	assert.EqualError(t, err, "Send: column 1 values do not match: value 0: expected click (string), got view (string), "+
+		"expectation is: Column(INSERT INTO events, 1)")
+}
+
+func TestBatchLifecycle(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectPrepareBatch("INSERT INTO events").WillBeSent().ExpectRows(3)
+	batch, err := mock.PrepareBatch(context.Background(), "INSERT INTO events")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.NoError(t, batch.Append(uint32(1)))
+	assert.NoError(t, batch.Append(uint32(2)))
+	assert.Equal(t, 2, batch.Rows())
+	assert.NoError(t, batch.Flush())
+	assert.Equal(t, 0, batch.Rows())
+	assert.NoError(t, batch.Append(uint32(3)))
+	assert.Equal(t, 1, batch.Rows())
+	assert.False(t, batch.IsSent())
+
+	assert.NoError(t, batch.Send())
+	assert.True(t, batch.IsSent())
+	assert.Equal(t, clickhouse.ErrBatchAlreadySent, batch.Append(uint32(4)))
+	assert.Equal(t, clickhouse.ErrBatchAlreadySent, batch.Send())
+	assert.Equal(t, clickhouse.ErrBatchAlreadySent, batch.Abort())
+	assert.NoError(t, batch.Close())
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectPrepareBatch("INSERT INTO events").WillReturnRows(5)
+	batch, err = mock.PrepareBatch(context.Background(), "INSERT INTO events")
+	if assert.NoError(t, err) {
+		assert.NoError(t, batch.Append(uint32(1)))
+		assert.Equal(t, 5, batch.Rows())
+	}
+}
+
+func TestBatchFailedSend(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	sendErr := errors.New("connection reset")
+	mock.ExpectPrepareBatch("INSERT INTO events").ExpectSend().WillReturnError(sendErr)
+	batch, err := mock.PrepareBatch(context.Background(), "INSERT INTO events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, sendErr, batch.Send())
+	assert.True(t, batch.IsSent())
+	assert.Equal(t, clickhouse.ErrBatchAlreadySent, batch.Send())
+	assert.Equal(t, clickhouse.ErrBatchAlreadySent, batch.Append(uint32(1)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchAbort(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectPrepareBatch("INSERT INTO events (id)").WillBeAborted()
+	batch, err := mock.PrepareBatch(context.Background(), "INSERT INTO events (id)")
+	if !assert.NoError(t, err) {
+		return
+	}
+	column := batch.Column(0)
+	assert.NoError(t, column.AppendRow(uint32(1)))
+	assert.NoError(t, batch.Abort())
+	assert.True(t, batch.IsSent())
+
+	assert.Equal(t, clickhouse.ErrBatchAlreadySent, batch.Append(uint32(2)))
+	assert.Equal(t, clickhouse.ErrBatchAlreadySent, column.AppendRow(uint32(2)))
+	assert.Equal(t, clickhouse.ErrBatchAlreadySent, batch.Send())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpectationsWereMetChecksBatchState(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectPrepareBatch("INSERT INTO events").WillBeAborted().ExpectRows(2)
+	batch, err := mock.PrepareBatch(context.Background(), "INSERT INTO events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, batch.Append(uint32(1)))
+	assert.NoError(t, batch.Send())
+
+	var unmet *UnmetExpectationsError
+	if assert.ErrorAs(t, mock.ExpectationsWereMet(), &unmet) && assert.Len(t, unmet.Unmet, 2) {
+		assert.Equal(t, "batch was not aborted", unmet.Unmet[0].Reason)
+		assert.Equal(t, "batch has 1 rows, expected 2", unmet.Unmet[1].Reason)
+	}
+}
+
+type event struct {
+	ID       uint32 `ch:"id"`
+	Name     string `ch:"name"`
+	internal string
+	Ignored  string `ch:"-"`
+}
+
+func TestBatchAppendStruct(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	prep := mock.ExpectPrepareBatch("INSERT INTO events (name, id)")
+	prep.ExpectAppendStruct()
+	prep.ExpectAppendStruct()
+	batch, err := mock.PrepareBatch(context.Background(), "INSERT INTO events (name, id)")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.NoError(t, batch.AppendStruct(&event{ID: 1, Name: "click"}))
+	err = batch.AppendStruct(event{ID: 2, Name: "view"})
+	assert.EqualError(t, err, "clickhouse [AppendStruct]: must pass a pointer, not a value, to AppendStruct destination")
+	assert.NoError(t, batch.AppendStruct(&event{ID: 2, Name: "view"}))
+	assert.Equal(t, 2, batch.Rows())
+	assert.Equal(t, [][]any{{"click", uint32(1)}, {"view", uint32(2)}}, prep.AppendedRows())
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectPrepareBatch("INSERT INTO events (id, name, at)")
+	batch, err = mock.PrepareBatch(context.Background(), "INSERT INTO events (id, name, at)")
+	if assert.NoError(t, err) {
+		err = batch.AppendStruct(&event{ID: 3})
+		assert.EqualError(t, err, `clickhouse [AppendStruct]: missing destination name "at" in *mockhouse.event`)
+	}
+
+	prep = mock.ExpectPrepareBatch("INSERT INTO events")
+	prep.ExpectAppendStruct()
+	batch, err = mock.PrepareBatch(context.Background(), "INSERT INTO events")
+	if assert.NoError(t, err) {
+		row := &event{ID: 4, Name: "scroll"}
+		assert.NoError(t, batch.AppendStruct(row))
+		assert.Equal(t, [][]any{{row}}, prep.AppendedRows())
+	}
+
+	columns := []ColumnType{{Name: "id", Type: "UInt32"}, {Name: "name", Type: "String"}}
+	prep = mock.ExpectPrepareBatch("INSERT INTO events").WithColumns(columns)
+	prep.ExpectAppendStruct()
+	batch, err = mock.PrepareBatch(context.Background(), "INSERT INTO events")
+	if assert.NoError(t, err) {
+		assert.NoError(t, batch.AppendStruct(&event{ID: 4, Name: "scroll"}))
+		assert.Equal(t, [][]any{{uint32(4), "scroll"}}, prep.AppendedRows())
+	}
+}
+
+func TestBatchWithColumns(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	columns := []ColumnType{{Name: "id", Type: "UInt64"}, {Name: "name", Type: "String"}, {Name: "at", Type: "DateTime"}}
+	prep := mock.ExpectPrepareBatch("INSERT INTO events (name, id)").WithColumns(columns)
+	prep.ExpectAppend()
+	prep.ExpectAppendStruct()
+	prep.ExpectSend()
+	batch, err := mock.PrepareBatch(context.Background(), "INSERT INTO events (name, id)")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if cols := batch.Columns(); assert.Len(t, cols, 2) {
+		assert.Equal(t, "name", cols[0].Name())
+		assert.Equal(t, "UInt64", string(cols[1].Type()))
+	}
+	assert.NoError(t, batch.Append("click", uint64(1)))
+	assert.NoError(t, batch.AppendStruct(&struct {
+		ID   uint64 `ch:"id"`
+		Name string `ch:"name"`
+	}{ID: 2, Name: "view"}))
+	assert.Equal(t, 2, batch.Rows())
+	assert.NoError(t, batch.Send())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchWithColumnsConversionErrors(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	columns := []ColumnType{{Name: "id", Type: "UInt64"}, {Name: "name", Type: "String"}}
+	mock.ExpectPrepareBatch("INSERT INTO events").WithColumns(columns)
+	batch, err := mock.PrepareBatch(context.Background(), "INSERT INTO events")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	err = batch.Append("1", "click")
+	var convErr *column.ColumnConverterError
+	if assert.ErrorAs(t, err, &convErr) {
+		assert.Equal(t, "UInt64", convErr.To)
+		assert.Equal(t, "string", convErr.From)
+	}
+	err = batch.Append(uint64(1), "click")
+	assert.ErrorIs(t, err, clickhouse.ErrBatchInvalid)
+	assert.ErrorIs(t, batch.Send(), clickhouse.ErrBatchInvalid)
+
+	mock.ExpectPrepareBatch("INSERT INTO events").WithColumns(columns)
+	batch, err = mock.PrepareBatch(context.Background(), "INSERT INTO events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	err = batch.Append(uint64(1))
+	assert.EqualError(t, err, "clickhouse [Append]:  clickhouse: expected 2 arguments, got 1")
+
+	mock.ExpectPrepareBatch("INSERT INTO events").WithColumns(columns)
+	batch, err = mock.PrepareBatch(context.Background(), "INSERT INTO events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	err = batch.Column(1).Append([]uint64{1})
+	if assert.ErrorAs(t, err, &convErr) {
+		assert.Equal(t, "String", convErr.To)
+	}
+
+	mock.ExpectPrepareBatch("INSERT INTO events").WithColumns(columns)
+	batch, err = mock.PrepareBatch(context.Background(), "INSERT INTO events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, batch.Column(0).Append([]uint64{1, 2}))
+	assert.NoError(t, batch.Column(1).Append([]string{"click"}))
+	assert.EqualError(t, batch.Send(), "clickhouse [Encode]:  mismatched len of columns - expected 2, received 1 for col name")
+
+	mock.ExpectPrepareBatch("INSERT INTO events (id, at)").WithColumns(columns)
+	_, err = mock.PrepareBatch(context.Background(), "INSERT INTO events (id, at)")
+	assert.EqualError(t, err, `clickhouse [PrepareBatch]: no such column "at" in the columns of the expectation`)
+}
@@ -0,0 +1,390 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	std_driver "database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// The binding below reproduces the one clickhouse-go renders arguments into
+// queries with, see https://github.com/ClickHouse/clickhouse-go/blob/v2.43.0/bind.go
+var (
+	hasQueryParamsRe = regexp.MustCompile("{.+:.+}")
+	bindNumericRe    = regexp.MustCompile(`\$[0-9]+`)
+	bindPositionalRe = regexp.MustCompile(`[^\\][?]`)
+	bindNamedRe      = regexp.MustCompile(`@[a-zA-Z0-9\_]+`)
+)
+
+// serverTimezone is the timezone of the server times are formatted for,
+// the mock reports none so it is UTC.
+var serverTimezone = time.UTC
+
+// bind renders the arguments of the call into its query the way clickhouse-go
+// does before sending it, setting cl.bound. It fails like clickhouse-go does:
+// arguments have to be either all named or all positional, a query may not
+// mix $N and ? placeholders and every placeholder needs an argument.
+func (cl *call) bind() error {
+	cl.bound = cl.query
+	if len(cl.args) == 0 {
+		return nil
+	}
+	// arguments are sent as server side parameters
+	// if any were set on the context already
+	if len(queryOptionsFromContext(cl.ctx).parameters) > 0 {
+		return nil
+	}
+	if hasQueryParamsRe.MatchString(cl.query) {
+		return bindParameters(cl.args)
+	}
+
+	bound, err := bind(serverTimezone, cl.query, cl.args)
+	if err != nil {
+		return err
+	}
+	cl.bound = bound
+	return nil
+}
+
+func bind(tz *time.Location, query string, args []any) (string, error) {
+	named, err := allNamed(args)
+	if err != nil {
+		return "", err
+	}
+	if named {
+		return bindNamed(tz, query, args)
+	}
+
+	numeric := bindNumericRe.MatchString(query)
+	if numeric && bindPositionalRe.MatchString(query) {
+		return "", clickhouse.ErrBindMixedParamsFormats
+	}
+	if numeric {
+		return bindNumeric(tz, query, args)
+	}
+	return bindPositional(tz, query, args)
+}
+
+// bindParameters checks arguments sent as values of {name:Type} parameters,
+// formatting the values which are not strings like clickhouse-go does.
+func bindParameters(args []any) error {
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case driver.NamedValue:
+			if _, ok := arg.Value.(string); ok {
+				continue
+			}
+			if _, err := format(serverTimezone, clickhouse.Seconds, arg.Value); err != nil {
+				return err
+			}
+		case driver.NamedDateValue:
+			if arg.Value.IsZero() || arg.Name == "" {
+				return clickhouse.ErrInvalidValueInNamedDateValue
+			}
+		default:
+			return clickhouse.ErrUnsupportedQueryParameter
+		}
+	}
+	return nil
+}
+
+// allNamed tells whether all arguments are named, failing
+// if only some of them are.
+func allNamed(args []any) (bool, error) {
+	var named, anonymous bool
+	for _, arg := range args {
+		switch arg.(type) {
+		case driver.NamedValue, driver.NamedDateValue:
+			named = true
+		default:
+			anonymous = true
+		}
+		if named && anonymous {
+			return true, clickhouse.ErrBindMixedParamsFormats
+		}
+	}
+	return named, nil
+}
+
+func bindPositional(tz *time.Location, query string, args []any) (string, error) {
+	var (
+		buf      strings.Builder
+		last     = -1 // position of the previous placeholder
+		argIndex int
+		unbound  int
+	)
+	for i := 0; i < len(query); i++ {
+		if query[i] != '?' {
+			continue
+		}
+		if i > 0 && query[i-1] == '\\' {
+			buf.WriteString(query[last+1 : i-1])
+			buf.WriteByte('?')
+		} else {
+			buf.WriteString(query[last+1 : i])
+			if argIndex < len(args) {
+				value, err := formatArg(tz, clickhouse.Seconds, args[argIndex])
+				if err != nil {
+					return "", err
+				}
+				buf.WriteString(value)
+				argIndex++
+			} else {
+				unbound++
+			}
+		}
+		last = i
+	}
+	if last < 0 {
+		return query, nil
+	}
+	buf.WriteString(query[last+1:])
+
+	if unbound > 0 {
+		return "", fmt.Errorf("have no arg for param ? at last %d positions", unbound)
+	}
+	return buf.String(), nil
+}
+
+func bindNumeric(tz *time.Location, query string, args []any) (string, error) {
+	params := make(map[string]string, len(args))
+	for i, arg := range args {
+		value, err := formatArg(tz, clickhouse.Seconds, arg)
+		if err != nil {
+			return "", err
+		}
+		params[fmt.Sprintf("$%d", i+1)] = value
+	}
+	return replaceParams(bindNumericRe, query, params, "have no arg for %s param")
+}
+
+func bindNamed(tz *time.Location, query string, args []any) (string, error) {
+	params := make(map[string]string, len(args))
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case driver.NamedValue:
+			value, err := formatArg(tz, clickhouse.Seconds, arg.Value)
+			if err != nil {
+				return "", err
+			}
+			params["@"+arg.Name] = value
+		case driver.NamedDateValue:
+			value, err := format(tz, clickhouse.TimeUnit(arg.Scale), arg.Value)
+			if err != nil {
+				return "", err
+			}
+			params["@"+arg.Name] = value
+		}
+	}
+	return replaceParams(bindNamedRe, query, params, "have no arg for %q param")
+}
+
+// replaceParams replaces the placeholders of query matched by re with their
+// values, failing with missing, formatted with the placeholder, if one has none.
+func replaceParams(re *regexp.Regexp, query string, params map[string]string, missing string) (string, error) {
+	var unbound string
+	query = re.ReplaceAllStringFunc(query, func(param string) string {
+		value, ok := params[param]
+		if !ok && unbound == "" {
+			unbound = param
+		}
+		return value
+	})
+	if unbound != "" {
+		return "", fmt.Errorf(missing, unbound)
+	}
+	return query, nil
+}
+
+// formatArg formats an argument, taking the value of a driver.Valuer.
+func formatArg(tz *time.Location, scale clickhouse.TimeUnit, v any) (string, error) {
+	if valuer, ok := v.(std_driver.Valuer); ok {
+		var err error
+		if v, err = valuer.Value(); err != nil {
+			return "", err
+		}
+	}
+	return format(tz, scale, v)
+}
+
+var stringQuoteReplacer = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+func quote(v string) string {
+	return "'" + stringQuoteReplacer.Replace(v) + "'"
+}
+
+// format renders a value as a ClickHouse SQL literal.
+func format(tz *time.Location, scale clickhouse.TimeUnit, v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return quote(v), nil
+	case time.Time:
+		return formatTime(tz, scale, v)
+	case *time.Time:
+		if v == nil {
+			return "NULL", nil
+		}
+		return formatTime(tz, scale, *v)
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case clickhouse.GroupSet:
+		val, err := formatJoin(tz, scale, v.Value)
+		if err != nil {
+			return "", err
+		}
+		return "(" + val + ")", nil
+	case []clickhouse.GroupSet:
+		return formatJoin(tz, scale, v)
+	case clickhouse.ArraySet:
+		val, err := formatJoin(tz, scale, v)
+		if err != nil {
+			return "", err
+		}
+		return "[" + val + "]", nil
+	case fmt.Stringer:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() &&
+			rv.Type().Elem().Implements(reflect.TypeOf((*fmt.Stringer)(nil)).Elem()) {
+			return "NULL", nil
+		}
+		return quote(v.String()), nil
+	case column.OrderedMap:
+		var values []string
+		for key := range v.Keys() {
+			value, _ := v.Get(key)
+			entry, err := formatEntry(tz, scale, key, value)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, entry)
+		}
+		return "map(" + strings.Join(values, ", ") + ")", nil
+	case column.IterableOrderedMap:
+		var values []string
+		for iter := v.Iterator(); iter.Next(); {
+			entry, err := formatEntry(tz, scale, iter.Key(), iter.Value())
+			if err != nil {
+				return "", err
+			}
+			values = append(values, entry)
+		}
+		return "map(" + strings.Join(values, ", ") + ")", nil
+	}
+
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.String:
+		return quote(rv.String()), nil
+	case reflect.Slice, reflect.Array:
+		values := make([]string, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			val, err := format(tz, scale, rv.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			values = append(values, val)
+		}
+		return "[" + strings.Join(values, ", ") + "]", nil
+	case reflect.Map:
+		values := make([]string, 0, rv.Len())
+		for _, key := range rv.MapKeys() {
+			name := fmt.Sprint(key.Interface())
+			if key.Kind() == reflect.String {
+				name = "'" + name + "'"
+			}
+			val, err := format(tz, scale, rv.MapIndex(key).Interface())
+			if err != nil {
+				return "", err
+			}
+			values = append(values, name+", "+val)
+		}
+		return "map(" + strings.Join(values, ", ") + ")", nil
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return "NULL", nil
+		}
+		return format(tz, scale, rv.Elem().Interface())
+	}
+	return fmt.Sprint(v), nil
+}
+
+func formatEntry(tz *time.Location, scale clickhouse.TimeUnit, key, value any) (string, error) {
+	k, err := format(tz, scale, key)
+	if err != nil {
+		return "", err
+	}
+	v, err := format(tz, scale, value)
+	if err != nil {
+		return "", err
+	}
+	return k + ", " + v, nil
+}
+
+func formatJoin[E any](tz *time.Location, scale clickhouse.TimeUnit, values []E) (string, error) {
+	items := make([]string, len(values))
+	for i := range values {
+		val, err := format(tz, scale, values[i])
+		if err != nil {
+			return "", err
+		}
+		items[i] = val
+	}
+	return strings.Join(items, ", "), nil
+}
+
+func formatTime(tz *time.Location, scale clickhouse.TimeUnit, value time.Time) (string, error) {
+	loc := value.Location().String()
+	digits := int(scale * 3)
+	layout := fmt.Sprintf("2006-01-02 15:04:05.%0*d", digits, 0)
+
+	switch loc {
+	case "Local", "":
+		if value.Unix() == 0 {
+			return "toDateTime(0)", nil
+		}
+		switch scale {
+		case clickhouse.MilliSeconds:
+			return fmt.Sprintf("toDateTime64('%d', 3)", value.UnixMilli()), nil
+		case clickhouse.MicroSeconds:
+			return fmt.Sprintf("toDateTime64('%d', 6)", value.UnixMicro()), nil
+		case clickhouse.NanoSeconds:
+			return fmt.Sprintf("toDateTime64('%d', 9)", value.UnixNano()), nil
+		}
+		return fmt.Sprintf("toDateTime('%d')", value.Unix()), nil
+	case tz.String():
+		if scale == clickhouse.Seconds {
+			return value.Format("toDateTime('2006-01-02 15:04:05')"), nil
+		}
+		return fmt.Sprintf("toDateTime64('%s', %d)", value.Format(layout), digits), nil
+	}
+
+	// the timezone name is quoted as is, so it must not need escaping
+	if stringQuoteReplacer.Replace(loc) != loc {
+		return "", fmt.Errorf("%w: %q", clickhouse.ErrInvalidTimezone, loc)
+	}
+	if scale == clickhouse.Seconds {
+		return fmt.Sprintf("toDateTime('%s', '%s')", value.Format("2006-01-02 15:04:05"), loc), nil
+	}
+	return fmt.Sprintf("toDateTime64('%s', %d, '%s')", value.Format(layout), digits, loc), nil
+}
@@ -0,0 +1,203 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBind(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		query string
+		args  []any
+		err   string
+	}{
+		{"SELECT 1", nil, ""},
+		{"SELECT ?", nil, ""},
+		{"SELECT ?, ?", []any{1, 2}, ""},
+		{"SELECT ?", []any{1, 2}, ""},
+		{"SELECT ?, ?, ?", []any{1}, "have no arg for param ? at last 2 positions"},
+		{`SELECT '\?', ?`, []any{1}, ""},
+		{"SELECT $1, $2", []any{1, 2}, ""},
+		{"SELECT $1, $3", []any{1, 2}, "have no arg for $3 param"},
+		{"SELECT $1, ?", []any{1, 2}, clickhouse.ErrBindMixedParamsFormats.Error()},
+		{"SELECT @id", []any{clickhouse.Named("id", 1)}, ""},
+		{"SELECT @id, @name", []any{clickhouse.Named("id", 1)}, `have no arg for "@name" param`},
+		{"SELECT @id, ?", []any{clickhouse.Named("id", 1), 2}, clickhouse.ErrBindMixedParamsFormats.Error()},
+		{"SELECT {id:UInt64}", []any{clickhouse.Named("id", 1)}, ""},
+		{"SELECT {id:UInt64}", []any{1}, clickhouse.ErrUnsupportedQueryParameter.Error()},
+		{"SELECT {at:DateTime}", []any{clickhouse.DateNamed("at", time.Time{}, clickhouse.Seconds)}, clickhouse.ErrInvalidValueInNamedDateValue.Error()},
+		{"SELECT {at:DateTime}", []any{driver.NamedDateValue{Name: "at", Value: time.Now()}}, ""},
+	} {
+		err := (&call{ctx: context.Background(), query: tc.query, args: tc.args}).bind()
+		if tc.err == "" {
+			assert.NoError(t, err, tc.query)
+		} else {
+			assert.EqualError(t, err, tc.err, tc.query)
+		}
+	}
+}
+
+func TestBindSkippedWithContextParameters(t *testing.T) {
+	t.Parallel()
+	ctx := clickhouse.Context(context.Background(), clickhouse.WithParameters(clickhouse.Parameters{"id": "1"}))
+	err := (&call{ctx: ctx, query: "SELECT {id:UInt64}, ?, ?", args: []any{1}}).bind()
+	assert.NoError(t, err)
+}
+
+func TestBindFormatsParameterValues(t *testing.T) {
+	t.Parallel()
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("bad'zone", 0))
+	err := (&call{ctx: context.Background(), query: "SELECT {at:DateTime}", args: []any{clickhouse.Named("at", at)}}).bind()
+	assert.ErrorIs(t, err, clickhouse.ErrInvalidTimezone)
+
+	err = (&call{ctx: context.Background(), query: "SELECT {name:String}", args: []any{clickhouse.Named("name", "it's")}}).bind()
+	assert.NoError(t, err)
+}
+
+func TestBindErrorsOnEveryQueryMethod(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.MatchExpectationsInOrder(false)
+
+	const query = "SELECT id FROM articles WHERE id = ? AND status = ?"
+	mock.ExpectQuery(query)
+	mock.ExpectExec(query)
+	mock.ExpectSelect(query)
+	mock.ExpectQueryRow(query)
+	mock.ExpectAsyncInsert(query, false)
+
+	ctx := context.Background()
+	_, err = mock.Query(ctx, query, 1)
+	assert.EqualError(t, err, "have no arg for param ? at last 1 positions")
+	assert.EqualError(t, mock.Exec(ctx, query, 1), "have no arg for param ? at last 1 positions")
+	var dest []uint64
+	assert.EqualError(t, mock.Select(ctx, &dest, query, 1), "have no arg for param ? at last 1 positions")
+	assert.EqualError(t, mock.QueryRow(ctx, query, 1).Err(), "have no arg for param ? at last 1 positions")
+	assert.ErrorIs(t, mock.AsyncInsert(ctx, query, false, clickhouse.Named("id", 1), "draft"), clickhouse.ErrBindMixedParamsFormats)
+
+	err = mock.ExpectationsWereMet()
+	if assert.Error(t, err, "malformed calls do not match their expectations") {
+		assert.Contains(t, err.Error(), "there are 5 remaining expectations which were not met")
+	}
+	assert.Len(t, mock.Calls().Failed(), 5)
+}
+
+type status int
+
+func (s status) String() string {
+	return [...]string{"draft", "published"}[s]
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	at := time.Date(2024, 3, 5, 14, 30, 15, 123456789, time.UTC)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("timezone database is not available")
+	}
+	title := "it's a \\ test"
+
+	for _, tc := range []struct {
+		value    any
+		expected string
+	}{
+		{nil, "NULL"},
+		{"O'Reilly", `'O\'Reilly'`},
+		{`C:\temp`, `'C:\\temp'`},
+		{&title, `'it\'s a \\ test'`},
+		{(*string)(nil), "NULL"},
+		{true, "1"},
+		{false, "0"},
+		{int64(-42), "-42"},
+		{3.5, "3.5"},
+		{status(1), "'published'"},
+		{at, "toDateTime('2024-03-05 14:30:15')"},
+		{at.In(berlin), "toDateTime('2024-03-05 15:30:15', 'Europe/Berlin')"},
+		{time.Unix(1709649015, 0).Local(), "toDateTime('1709649015')"},
+		{[]string{"a", "b'"}, `['a', 'b\'']`},
+		{[]any{1, "a", nil}, "[1, 'a', NULL]"},
+		{[][]int{{1, 2}, {3}}, "[[1, 2], [3]]"},
+		{clickhouse.GroupSet{Value: []any{1, "a"}}, "(1, 'a')"},
+		{[]clickhouse.GroupSet{{Value: []any{1, "a"}}, {Value: []any{2, "b"}}}, "(1, 'a'), (2, 'b')"},
+		{clickhouse.ArraySet{1, "a"}, "[1, 'a']"},
+		{map[string]int{"a": 1}, "map('a', 1)"},
+	} {
+		formatted, err := format(serverTimezone, clickhouse.Seconds, tc.value)
+		if assert.NoError(t, err) {
+			assert.Equal(t, tc.expected, formatted, "%#v", tc.value)
+		}
+	}
+
+	formatted, err := format(serverTimezone, clickhouse.MilliSeconds, at)
+	assert.NoError(t, err)
+	assert.Equal(t, "toDateTime64('2024-03-05 14:30:15.123', 3)", formatted)
+}
+
+func TestBindRendersArguments(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		query string
+		args  []any
+		bound string
+	}{
+		{"SELECT ?", nil, "SELECT ?"},
+		{"SELECT * FROM t WHERE a = ? AND b IN ?", []any{"x", []int{1, 2}}, "SELECT * FROM t WHERE a = 'x' AND b IN [1, 2]"},
+		{`SELECT '\?', ?`, []any{1}, `SELECT '?', 1`},
+		{"SELECT $2, $1, $2", []any{"a", "b"}, "SELECT 'b', 'a', 'b'"},
+		{"SELECT @id, @id", []any{clickhouse.Named("id", 7)}, "SELECT 7, 7"},
+		{"SELECT {id:UInt64}", []any{clickhouse.Named("id", 7)}, "SELECT {id:UInt64}"},
+	} {
+		cl := &call{ctx: context.Background(), query: tc.query, args: tc.args}
+		if assert.NoError(t, cl.bind(), tc.query) {
+			assert.Equal(t, tc.bound, cl.bound)
+		}
+	}
+}
+
+func TestExpectBoundQuery(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectBoundQuery(`SELECT id FROM articles WHERE title = 'Robert\'); DROP TABLE articles; --' AND created > toDateTime('2024-03-05 14:30:15')`)
+	mock.ExpectExec("ALTER TABLE articles DELETE WHERE id IN [1, 2, 3]").MatchBound()
+	mock.ExpectExec("ALTER TABLE articles DELETE WHERE id IN ?").WithArgs([]int{4})
+
+	ctx := context.Background()
+	at := time.Date(2024, 3, 5, 14, 30, 15, 0, time.UTC)
+	_, err = mock.Query(ctx, "SELECT id FROM articles WHERE title = ? AND created > ?", "Robert'); DROP TABLE articles; --", at)
+	assert.NoError(t, err)
+
+	err = mock.Exec(ctx, "ALTER TABLE articles DELETE WHERE id IN ?", []int{1, 2})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `actual sql: "ALTER TABLE articles DELETE WHERE id IN [1, 2]" does not equal to expected "ALTER TABLE articles DELETE WHERE id IN [1, 2, 3]"`)
+	}
+	assert.NoError(t, mock.Exec(ctx, "ALTER TABLE articles DELETE WHERE id IN ?", []int{1, 2, 3}))
+	assert.NoError(t, mock.Exec(ctx, "ALTER TABLE articles DELETE WHERE id IN ?", []int{4}), "other expectations match the query as it is")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, "ALTER TABLE articles DELETE WHERE id IN [4]", mock.Calls()[3].BoundQuery)
+}
@@ -0,0 +1,206 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// Call is a recorded invocation of a driver.Conn or driver.Batch method.
+type Call struct {
+	// Method is the name of the invoked method, e.g. "Query" or "Append".
+	Method string
+	// Query is the SQL of the call. For calls made on a batch
+	// it is the query the batch was prepared with.
+	Query string
+	// Args are the arguments of the call, or the appended values
+	// for Append.
+	Args []any
+	// BoundQuery is the query with the arguments rendered into it
+	// as clickhouse-go would send it. It is the same as Query for
+	// calls whose arguments are not rendered into their query.
+	BoundQuery string
+	// Batch is set for calls made on a driver.Batch.
+	Batch bool
+	// Settings and QueryID are taken from the clickhouse.Context
+	// of the call, or of PrepareBatch for calls made on a batch.
+	Settings clickhouse.Settings
+	QueryID  string
+	// Async is set for AsyncInsert calls and for Exec calls made with
+	// clickhouse.WithAsync, which clickhouse-go sends as async inserts.
+	// Wait is the wait flag they were made with.
+	Async bool
+	Wait  bool
+	// Values are the rows of the VALUES clause of async inserts, with the
+	// arguments rendered into it. NULL is read as nil, numbers as int64,
+	// uint64 or float64, arrays and tuples as []any and expressions other
+	// than literals as their SQL.
+	Values [][]any
+	// ExternalTables are the tables shipped alongside the call
+	// with clickhouse.WithExternalTable.
+	ExternalTables []ExternalTable
+	// Start and End are the times the call started and returned.
+	Start time.Time
+	End   time.Time
+	// Expectation is the expectation the call matched,
+	// nil if the call was not expected.
+	Expectation Expectation
+	// Err is the error the call returned.
+	Err error
+}
+
+// Duration returns how long the call took.
+func (c Call) Duration() time.Duration {
+	return c.End.Sub(c.Start)
+}
+
+// Expected reports whether the call matched an expectation.
+func (c Call) Expected() bool {
+	return c.Expectation != nil
+}
+
+func (c Call) String() string {
+	var msg string
+	if c.Batch {
+		msg = fmt.Sprintf("batch %s with query '%s'", c.Method, c.Query)
+	} else {
+		msg = (&call{method: c.Method, query: c.Query}).String()
+	}
+	if len(c.Args) > 0 {
+		msg += fmt.Sprintf(" with args %v", c.Args)
+	}
+	if c.Err != nil {
+		msg += fmt.Sprintf(", returned error: %v", c.Err)
+	}
+	return msg
+}
+
+// CallList is a list of recorded calls.
+type CallList []Call
+
+// Filter returns the calls for which keep returns true.
+func (l CallList) Filter(keep func(Call) bool) CallList {
+	var calls CallList
+	for _, c := range l {
+		if keep(c) {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// ByMethod returns the calls of any of the given methods.
+func (l CallList) ByMethod(methods ...string) CallList {
+	return l.Filter(func(c Call) bool {
+		for _, m := range methods {
+			if c.Method == m {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ByQuery returns the calls whose query contains substr.
+func (l CallList) ByQuery(substr string) CallList {
+	return l.Filter(func(c Call) bool {
+		return strings.Contains(c.Query, substr)
+	})
+}
+
+// ByQueryID returns the calls made with the given query ID.
+func (l CallList) ByQueryID(id string) CallList {
+	return l.Filter(func(c Call) bool {
+		return c.QueryID == id
+	})
+}
+
+// ByExpectation returns the calls which matched e.
+func (l CallList) ByExpectation(e Expectation) CallList {
+	return l.Filter(func(c Call) bool {
+		return c.Expectation == e
+	})
+}
+
+// Batches returns the calls made on a driver.Batch.
+func (l CallList) Batches() CallList {
+	return l.Filter(func(c Call) bool {
+		return c.Batch
+	})
+}
+
+// Unexpected returns the calls which did not match any expectation.
+func (l CallList) Unexpected() CallList {
+	return l.Filter(func(c Call) bool {
+		return !c.Expected()
+	})
+}
+
+// Failed returns the calls which returned an error.
+func (l CallList) Failed() CallList {
+	return l.Filter(func(c Call) bool {
+		return c.Err != nil
+	})
+}
+
+// Queries returns the queries of the calls.
+func (l CallList) Queries() []string {
+	queries := make([]string, len(l))
+	for i, c := range l {
+		queries[i] = c.Query
+	}
+	return queries
+}
+
+// Calls returns the recorded calls, see ClickConnMockCommon.Calls.
+func (c *clickhousemock) Calls() CallList {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	calls := make(CallList, len(c.calls))
+	copy(calls, c.calls)
+	return calls
+}
+
+// record adds cl, which returned *err, to the call history.
+func (c *clickhousemock) record(cl *call, err *error) {
+	opts := cl.options()
+	rec := Call{
+		Method:         cl.method,
+		Query:          cl.query,
+		Args:           cl.args,
+		BoundQuery:     cl.bound,
+		Batch:          cl.batch,
+		Settings:       opts.settings,
+		QueryID:        opts.queryID,
+		ExternalTables: externalTables(opts.external),
+		Async:          cl.async,
+		Wait:           cl.wait,
+		Start:          cl.start,
+		End:            time.Now(),
+		Err:            *err,
+	}
+	if cl.ex != nil {
+		rec.Expectation = cl.ex
+	}
+	if cl.async {
+		rec.Values, _ = insertValues(cl.bound)
+	}
+
+	c.mu.Lock()
+	c.calls = append(c.calls, rec)
+	c.mu.Unlock()
+}
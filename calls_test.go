@@ -0,0 +1,129 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallsRecordsConnCalls(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	exec := mock.ExpectExec("TRUNCATE TABLE jobs")
+	mock.ExpectQuery("SELECT id FROM jobs").WillReturnError(errors.New("boom"))
+
+	ctx := clickhouse.Context(context.Background(),
+		clickhouse.WithQueryID("truncate-1"),
+		clickhouse.WithSettings(clickhouse.Settings{"max_threads": 2}),
+	)
+	assert.NoError(t, mock.Exec(ctx, "TRUNCATE TABLE jobs"))
+	_, err = mock.Query(context.Background(), "SELECT id FROM jobs")
+	assert.EqualError(t, err, "boom")
+	assert.Error(t, mock.Exec(context.Background(), "DROP TABLE jobs", 1))
+
+	calls := mock.Calls()
+	if !assert.Len(t, calls, 3) {
+		return
+	}
+
+	assert.Equal(t, "Exec", calls[0].Method)
+	assert.Equal(t, "TRUNCATE TABLE jobs", calls[0].Query)
+	assert.Equal(t, "truncate-1", calls[0].QueryID)
+	assert.Equal(t, clickhouse.Settings{"max_threads": 2}, calls[0].Settings)
+	assert.Equal(t, Expectation(exec), calls[0].Expectation)
+	assert.NoError(t, calls[0].Err)
+	assert.False(t, calls[0].Start.IsZero())
+	assert.False(t, calls[0].End.Before(calls[0].Start))
+
+	assert.Equal(t, "Query", calls[1].Method)
+	assert.True(t, calls[1].Expected())
+	assert.EqualError(t, calls[1].Err, "boom")
+
+	assert.Equal(t, []any{1}, calls[2].Args)
+	assert.False(t, calls[2].Expected())
+	assert.Error(t, calls[2].Err)
+}
+
+func TestCallsRecordsBatchCalls(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	prep := mock.ExpectPrepareBatch("INSERT INTO jobs")
+	appendRow := prep.ExpectAppend()
+	prep.ExpectSend()
+
+	ctx := clickhouse.Context(context.Background(), clickhouse.WithQueryID("insert-1"))
+	b, err := mock.PrepareBatch(ctx, "INSERT INTO jobs")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, b.Append(int32(1), "first"))
+	assert.NoError(t, b.Send())
+
+	calls := mock.Calls()
+	assert.Equal(t, []string{"PrepareBatch", "Append", "Send"}, []string{calls[0].Method, calls[1].Method, calls[2].Method})
+
+	batch := calls.Batches()
+	if !assert.Len(t, batch, 2) {
+		return
+	}
+	assert.Equal(t, "INSERT INTO jobs", batch[0].Query)
+	assert.Equal(t, []any{int32(1), "first"}, batch[0].Args)
+	assert.Equal(t, "insert-1", batch[0].QueryID)
+	assert.Equal(t, Expectation(appendRow), batch[0].Expectation)
+	assert.Equal(t, "batch Append with query 'INSERT INTO jobs' with args [1 first]", batch[0].String())
+}
+
+func TestCallListFilters(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	insert := mock.ExpectExec("INSERT INTO jobs").Times(2)
+	mock.ExpectQuery("SELECT id FROM jobs")
+
+	ctx := clickhouse.Context(context.Background(), clickhouse.WithQueryID("q-1"))
+	assert.NoError(t, mock.Exec(ctx, "INSERT INTO jobs"))
+	assert.NoError(t, mock.Exec(context.Background(), "INSERT INTO jobs"))
+	rows, err := mock.Query(context.Background(), "SELECT id FROM jobs")
+	if assert.NoError(t, err) {
+		assert.NoError(t, rows.Close())
+	}
+	assert.Error(t, mock.Exec(context.Background(), "DELETE FROM jobs"))
+	assert.NoError(t, mock.Ping(context.Background()))
+
+	calls := mock.Calls()
+	assert.Empty(t, calls.ByMethod("Ping"))
+	assert.Len(t, calls.ByMethod("Exec"), 3)
+	assert.Len(t, calls.ByMethod("Exec", "Query"), 4)
+	assert.Equal(t, []string{"SELECT id FROM jobs"}, calls.ByQuery("SELECT").Queries())
+	assert.Len(t, calls.ByQueryID("q-1"), 1)
+	assert.Len(t, calls.ByExpectation(insert), 2)
+	assert.Equal(t, []string{"DELETE FROM jobs"}, calls.Unexpected().Queries())
+	assert.Equal(t, []string{"DELETE FROM jobs"}, calls.Failed().Queries())
+	assert.Empty(t, calls.Batches())
+}
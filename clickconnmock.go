@@ -14,14 +14,16 @@ package mockhouse
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"sync"
+	"testing"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
-	"github.com/DATA-DOG/go-sqlmock"
 )
 
 type OpError struct { // taken from https://github.com/ClickHouse/clickhouse-go/blob/bfd9f33931482ddacadee5a899b760455b9268e6/clickhouse.go#L51
@@ -88,6 +90,11 @@ type ClickConnMockCommon interface {
 	// the *ExpectedQuery allows to mock database response.
 	ExpectQuery(expectedSQL string) *ExpectedQuery
 
+	// ExpectBoundQuery expects Query() to be called with a query which,
+	// once its arguments are rendered into it the way clickhouse-go
+	// does, matches expectedSQL.
+	ExpectBoundQuery(expectedSQL string) *ExpectedQuery
+
 	// ExpectSelect expects Select() to be called with expectedSQL query.
 	// the *ExpectedSelect allows to mock database response.
 	ExpectSelect(expectedSQL string) *ExpectedSelect
@@ -104,6 +111,8 @@ type ClickConnMockCommon interface {
 
 	// ExpectationsWereMet checks whether all queued expectations
 	// were met in order. If any of them was not met - an error is returned.
+	// The error is an *UnmetExpectationsError listing every unmet
+	// expectation, including the steps expected on prepared batches.
 	ExpectationsWereMet() error
 
 	// MatchExpectationsInOrder gives an option whether to match all
@@ -117,7 +126,25 @@ type ClickConnMockCommon interface {
 	// as it is switched to false, expectations will be matched
 	// in any order. Or otherwise if switched to true, any unmatched
 	// expectations will be expected in order
+	//
+	// Expectations grouped with InOrder or AnyOrder follow the
+	// ordering of their group instead.
 	MatchExpectationsInOrder(bool)
+
+	// InOrder expects the given expectations to be matched in
+	// the given order, while calls matching other expectations
+	// may interleave with them freely.
+	InOrder(exps ...Expectation)
+
+	// AnyOrder allows the given expectations to be matched in
+	// any order, while calls matching other expectations may
+	// interleave with them freely.
+	AnyOrder(exps ...Expectation)
+
+	// Calls returns every call made on the connection and on the
+	// batches it prepared so far, in the order they were made,
+	// whether they matched an expectation or not.
+	Calls() CallList
 }
 
 type clickhousemock struct {
@@ -125,10 +152,17 @@ type clickhousemock struct {
 	dsn          string
 	opened       int
 	drv          *mockClickHouseDriver
-	queryMatcher sqlmock.QueryMatcher
+	queryMatcher QueryMatcher
 	monitorPings bool
+	t            testing.TB
+
+	// expectedMu guards expected and ordered, it is held for
+	// the whole lookup of a call so that matching is atomic
+	expectedMu sync.Mutex
+	expected   []expectation
 
-	expected []expectation
+	mu    sync.Mutex
+	calls []Call
 }
 
 var _ ClickConnMockCommon = (*clickhousemock)(nil)
@@ -136,22 +170,27 @@ var _ driver.Conn = (*clickhousemock)(nil)
 
 func (c *clickhousemock) open(options *clickhouse.Options) (*clickhousemock, error) {
 	if c.queryMatcher == nil {
-		c.queryMatcher = sqlmock.QueryMatcherRegexp
+		c.queryMatcher = MatchRegexp
 	}
 	return c, nil
 }
 
-func (c *clickhousemock) queryMatcherFunc() sqlmock.QueryMatcher {
-	return c.queryMatcher
-}
-
 func (c *clickhousemock) MatchExpectationsInOrder(b bool) {
+	c.expectedMu.Lock()
 	c.ordered = b
+	c.expectedMu.Unlock()
+}
+
+// expect queues ex after the expectations already set.
+func (c *clickhousemock) expect(ex expectation) {
+	c.expectedMu.Lock()
+	c.expected = append(c.expected, ex)
+	c.expectedMu.Unlock()
 }
 
 func (c *clickhousemock) ExpectClose() *ExpectedClose {
 	e := &ExpectedClose{}
-	c.expected = append(c.expected, e)
+	c.expect(e)
 	return e
 }
 
@@ -159,140 +198,73 @@ func (c *clickhousemock) ExpectClose() *ExpectedClose {
 // be called depending on the circumstances, but if it is called
 // there must be an *ExpectedClose expectation satisfied.
 // meets https://pkg.go.dev/github.com/ClickHouse/clickhouse-go/v2/lib/driver#Conn interface
-func (c *clickhousemock) Close() error {
-	c.drv.Lock()
-	defer c.drv.Unlock()
+func (c *clickhousemock) Close() (err error) {
+	cl := newCall(context.Background(), "Close", "", nil)
+	defer c.record(cl, &err)
 
+	c.drv.Lock()
 	c.opened--
 	if c.opened == 0 {
 		delete(c.drv.conns, c.dsn)
 	}
+	c.drv.Unlock()
 
-	var expected *ExpectedClose
-	var fulfilled int
-	var ok bool
-	for _, next := range c.expected {
-		next.Lock()
-		if next.fulfilled() {
-			next.Unlock()
-			fulfilled++
-			continue
-		}
-
-		if expected, ok = next.(*ExpectedClose); ok {
-			break
-		}
-
-		next.Unlock()
-		if c.ordered {
-			return fmt.Errorf("call to database Close, was not expected, next expectation is: %s", next)
-		}
+	ex, err := c.match(cl)
+	if ex == nil {
+		return err
 	}
-
-	if expected == nil {
-		msg := "call to database Close was not expected"
-		if fulfilled == len(c.expected) {
-			msg = "all expectations were already fulfilled, " + msg
-		}
-		return fmt.Errorf(msg)
+	if werr := delayResult(context.Background(), ex); werr != nil {
+		return werr
 	}
-
-	expected.triggered = true
-	expected.Unlock()
-	return expected.err
+	return err
 }
 
 func (c *clickhousemock) ExpectStats() *ExpectedStats {
 	e := &ExpectedStats{}
-	c.expected = append(c.expected, e)
+	c.expect(e)
 	return e
 }
 
 // Stats meets https://pkg.go.dev/github.com/ClickHouse/clickhouse-go/v2/lib/driver#Conn interface
 func (c *clickhousemock) Stats() driver.Stats {
-	c.drv.Lock()
-	defer c.drv.Unlock()
-
-	var expected *ExpectedStats
-	var fulfilled int
-	var ok bool
-	for _, next := range c.expected {
-		next.Lock()
-		if next.fulfilled() {
-			next.Unlock()
-			fulfilled++
-			continue
-		}
+	var err error
+	cl := newCall(context.Background(), "Stats", "", nil)
+	defer c.record(cl, &err)
 
-		if expected, ok = next.(*ExpectedStats); ok {
-			break
+	ex, err := c.match(cl)
+	if ex == nil {
+		if c.t == nil {
+			panic(err)
 		}
-
-		next.Unlock()
-		if c.ordered {
-			panic(fmt.Errorf("call to database Stats, was not expected, next expectation is: %s", next))
-		}
-	}
-
-	if expected == nil {
-		msg := "call to database Stats was not expected"
-		if fulfilled == len(c.expected) {
-			msg = "all expectations were already fulfilled, " + msg
-		}
-		panic((msg))
+		return driver.Stats{}
 	}
-
-	expected.triggered = true
-	expected.Unlock()
-	return expected.stats
+	delayResult(context.Background(), ex)
+	return ex.(*ExpectedStats).stats
 }
 
 // Ping meets https://pkg.go.dev/github.com/ClickHouse/clickhouse-go/v2/lib/driver#Conn interface
-func (c *clickhousemock) Ping(ctx context.Context) error {
+func (c *clickhousemock) Ping(ctx context.Context) (err error) {
+	// pings which are not monitored are not expected
+	// either, so they are left out of Calls too
 	if !c.monitorPings {
 		return nil
 	}
+	cl := newCall(ctx, "Ping", "", nil)
+	defer c.record(cl, &err)
 
-	c.drv.Lock()
-	defer c.drv.Unlock()
-
-	var expected *ExpectedPing
-	var fulfilled int
-	var ok bool
-	for _, next := range c.expected {
-		next.Lock()
-		if next.fulfilled() {
-			next.Unlock()
-			fulfilled++
-			continue
-		}
-
-		if expected, ok = next.(*ExpectedPing); ok {
-			break
-		}
-
-		next.Unlock()
-		if c.ordered {
-			panic(fmt.Errorf("call to database Ping, was not expected, next expectation is: %s", next))
-		}
+	ex, err := c.match(cl)
+	if ex == nil {
+		return err
 	}
-
-	if expected == nil {
-		msg := "call to database Ping was not expected"
-		if fulfilled == len(c.expected) {
-			msg = "all expectations were already fulfilled, " + msg
-		}
-		panic(fmt.Errorf(msg))
+	if werr := delayResult(ctx, ex); werr != nil {
+		return werr
 	}
-
-	expected.triggered = true
-	expected.Unlock()
-	return expected.err
+	return err
 }
 
 func (c *clickhousemock) ExpectPing() *ExpectedPing {
 	e := &ExpectedPing{}
-	c.expected = append(c.expected, e)
+	c.expect(e)
 	return e
 }
 
@@ -300,345 +272,209 @@ func (c *clickhousemock) ExpectAsyncInsert(expectedSQL string, expectedWait bool
 	e := &ExpectedAsyncInsert{}
 	e.expectSQL = expectedSQL
 	e.expectWait = expectedWait
-	c.expected = append(c.expected, e)
+	c.expect(e)
 	return e
 }
 
 // AsyncInsert meets https://pkg.go.dev/github.com/ClickHouse/clickhouse-go/v2/lib/driver#Conn interface
-func (c *clickhousemock) AsyncInsert(ctx context.Context, query string, wait bool, args ...any) error {
-	ex, err := c.asyncInsert(ctx, query, wait)
-	if ex != nil {
-		select {
-		case <-time.After(ex.delay):
-			return err
-		case <-ctx.Done():
-			return ctx.Err()
-		}
-	}
-	return err
-}
-
-func (c *clickhousemock) asyncInsert(ctx context.Context, query string, wait bool) (*ExpectedAsyncInsert, error) {
-	var expected *ExpectedAsyncInsert
-	var fulfilled int
-	var ok bool
-
-	for _, next := range c.expected {
-		next.Lock()
-		if next.fulfilled() {
-			next.Unlock()
-			fulfilled++
-			continue
-		}
-
-		if c.ordered {
-			if expected, ok = next.(*ExpectedAsyncInsert); ok {
-				break
-			}
+func (c *clickhousemock) AsyncInsert(ctx context.Context, query string, wait bool, args ...any) (err error) {
+	cl := newCall(ctx, "AsyncInsert", query, args)
+	cl.async, cl.wait = true, wait
+	defer c.record(cl, &err)
 
-			next.Unlock()
-			return nil, fmt.Errorf("call to AsyncInsert statement with query '%s', was not expected, next expectation is: %s", query, next)
-		}
-
-		if pr, ok := next.(*ExpectedAsyncInsert); ok {
-			if err := c.queryMatcher.Match(pr.expectSQL, query); err == nil {
-				expected = pr
-				break
-			}
-		}
-		next.Unlock()
+	if err = cl.bind(); err != nil {
+		return err
 	}
 
-	if expected == nil {
-		msg := "call to AsyncInsert '%s' query was not expected"
-		if fulfilled == len(c.expected) {
-			msg = "all expectations were already fulfilled, " + msg
-		}
-		return nil, fmt.Errorf(msg, query)
+	ex, err := c.match(cl)
+	if ex == nil {
+		return err
 	}
-	defer expected.Unlock()
-	if err := c.queryMatcher.Match(expected.expectSQL, query); err != nil {
-		return nil, fmt.Errorf("AsyncInsert: %v", err)
+	if werr := delayResult(ctx, ex); werr != nil {
+		return werr
 	}
-
-	expected.triggered = true
-	return expected, expected.err
+	return err
 }
 
 func (c *clickhousemock) ExpectExec(expectedSQL string) *ExpectedExec {
 	e := &ExpectedExec{}
 	e.expectSQL = expectedSQL
-	c.expected = append(c.expected, e)
+	c.expect(e)
 	return e
 }
 
 // Exec meets https://pkg.go.dev/github.com/ClickHouse/clickhouse-go/v2/lib/driver#Conn interface
-func (c *clickhousemock) Exec(ctx context.Context, query string, args ...any) error {
-	c.drv.Lock()
-	defer c.drv.Unlock()
+func (c *clickhousemock) Exec(ctx context.Context, query string, args ...any) (err error) {
+	cl := newCall(ctx, "Exec", query, args)
+	// clickhouse-go sends Exec calls made with clickhouse.WithAsync as async inserts
+	opts := queryOptionsFromContext(ctx)
+	cl.async, cl.wait = opts.async, opts.async && opts.asyncWait
+	defer c.record(cl, &err)
 
-	var expected *ExpectedExec
-	var fulfilled int
-	var ok bool
-	for _, next := range c.expected {
-		next.Lock()
-		if next.fulfilled() {
-			next.Unlock()
-			fulfilled++
-			continue
-		}
-
-		if expected, ok = next.(*ExpectedExec); ok {
-			break
-		}
-
-		next.Unlock()
-		if c.ordered {
-			return fmt.Errorf("call to database Exec, was not expected, next expectation is: %s", next)
-		}
+	if err = cl.bind(); err != nil {
+		return err
 	}
 
-	if expected == nil {
-		msg := "call to database Exec was not expected"
-		if fulfilled == len(c.expected) {
-			msg = "all expectations were already fulfilled, " + msg
-		}
-		return fmt.Errorf(msg)
+	ex, err := c.match(cl)
+	if ex == nil {
+		return err
 	}
-
-	if err := c.queryMatcherFunc().Match(expected.expectSQL, query); err != nil {
-		expected.Unlock()
-		return fmt.Errorf("call to database Exec with unexpected query: %s", query)
+	if werr := delayResult(ctx, ex); werr != nil {
+		return werr
 	}
-
-	expected.triggered = true
-	expected.Unlock()
-	return expected.err
+	if err != nil {
+		return err
+	}
+	if expected, ok := ex.(*ExpectedExec); ok {
+		expected.events.emit(ctx)
+	}
+	return nil
 }
 
 func (c *clickhousemock) ExpectPrepareBatch(expectedSQL string) *ExpectedPrepareBatch {
-	e := &ExpectedPrepareBatch{expectSQL: expectedSQL}
-	c.expected = append(c.expected, e)
+	e := &ExpectedPrepareBatch{}
+	e.expectSQL = expectedSQL
+	c.expect(e)
 	return e
 }
 
 // PrepareBatch meets https://pkg.go.dev/github.com/ClickHouse/clickhouse-go/v2/lib/driver#Conn interface
-func (c *clickhousemock) PrepareBatch(ctx context.Context, query string, opts ...driver.PrepareBatchOption) (driver.Batch, error) {
-	ex, err := c.prepareBatch(ctx, query)
-	if ex != nil {
-		select {
-		case <-time.After(ex.delay):
-			if err != nil {
-				return nil, err
-			}
-			return &batch{c, ex, query}, nil
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		}
-	}
-	return nil, err
-}
-
-func (c *clickhousemock) prepareBatch(ctx context.Context, query string) (*ExpectedPrepareBatch, error) {
-	var expected *ExpectedPrepareBatch
-	var fulfilled int
-	var ok bool
-
-	for _, next := range c.expected {
-		next.Lock()
-		if next.fulfilled() {
-			next.Unlock()
-			fulfilled++
-			continue
-		}
-
-		if c.ordered {
-			if expected, ok = next.(*ExpectedPrepareBatch); ok {
-				break
-			}
-
-			next.Unlock()
-			return nil, fmt.Errorf("call to Prepare statement with query '%s', was not expected, next expectation is: %s", query, next)
-		}
+func (c *clickhousemock) PrepareBatch(ctx context.Context, query string, opts ...driver.PrepareBatchOption) (_ driver.Batch, err error) {
+	cl := newCall(ctx, "PrepareBatch", query, nil)
+	defer c.record(cl, &err)
 
-		if pr, ok := next.(*ExpectedPrepareBatch); ok {
-			if err := c.queryMatcher.Match(pr.expectSQL, query); err == nil {
-				expected = pr
-				break
-			}
-		}
-		next.Unlock()
+	ex, err := c.match(cl)
+	if ex == nil {
+		return nil, err
 	}
-
-	if expected == nil {
-		msg := "call to Prepare '%s' query was not expected"
-		if fulfilled == len(c.expected) {
-			msg = "all expectations were already fulfilled, " + msg
-		}
-		return nil, fmt.Errorf(msg, query)
+	if werr := delayResult(ctx, ex); werr != nil {
+		return nil, werr
 	}
-	defer expected.Unlock()
-	if err := c.queryMatcherFunc().Match(expected.expectSQL, query); err != nil {
+	if err != nil {
 		return nil, err
 	}
-
-	expected.triggered = true
-	return expected, expected.err
+	expected := ex.(*ExpectedPrepareBatch)
+	block, names, err := newBatchBlock(expected, query)
+	if err != nil {
+		return nil, err
+	}
+	return &batch{
+		conn:      c,
+		ex:        expected,
+		query:     query,
+		ctx:       ctx,
+		structMap: newStructMap(),
+		names:     names,
+		block:     block,
+	}, nil
 }
 
 func (c *clickhousemock) ExpectQueryRow(expectedSQL string) *ExpectedQueryRow {
 	e := &ExpectedQueryRow{}
 	e.expectSQL = expectedSQL
-	c.expected = append(c.expected, e)
+	c.expect(e)
 	return e
 }
 
 // QueryRow meets https://pkg.go.dev/github.com/ClickHouse/clickhouse-go/v2/lib/driver#Conn interface
 func (c *clickhousemock) QueryRow(ctx context.Context, query string, args ...any) driver.Row {
-	ex, err := c.queryRow(ctx, query, args...)
-	if ex != nil {
-		ex.row.err = err
-	}
-	if ex != nil {
-		select {
-		case <-time.After(ex.delay):
-		case <-ctx.Done():
-		}
-	}
-	return ex.row
+	cl := newCall(ctx, "QueryRow", query, args)
+	row := c.queryRow(cl)
+	c.record(cl, &row.err)
+	return row
 }
 
-func (c *clickhousemock) queryRow(ctx context.Context, query string, args ...any) (*ExpectedQueryRow, error) {
-	var expected *ExpectedQueryRow
-	var fulfilled int
-	var ok bool
-
-	for _, next := range c.expected {
-		next.Lock()
-		if next.fulfilled() {
-			next.Unlock()
-			fulfilled++
-			continue
-		}
-
-		if c.ordered {
-			if expected, ok = next.(*ExpectedQueryRow); ok {
-				break
-			}
-
-			next.Unlock()
-			return nil, fmt.Errorf("call to QueryRow statement with query '%s', was not expected, next expectation is: %s", query, next)
-		}
+func (c *clickhousemock) queryRow(cl *call) *Row {
+	if err := cl.bind(); err != nil {
+		return &Row{err: err}
+	}
+	ex, err := c.match(cl)
+	if ex == nil {
+		return &Row{err: err}
+	}
+	if werr := delayResult(cl.ctx, ex); werr != nil {
+		return &Row{err: werr}
+	}
 
-		if pr, ok := next.(*ExpectedQueryRow); ok {
-			if err := c.queryMatcher.Match(pr.expectSQL, query); err == nil {
-				expected = pr
-				break
-			}
-		}
-		next.Unlock()
+	if err != nil {
+		return &Row{err: err}
 	}
 
-	if expected == nil {
-		msg := "call to QueryRow '%s' query was not expected"
-		if fulfilled == len(c.expected) {
-			msg = "all expectations were already fulfilled, " + msg
-		}
-		return nil, fmt.Errorf(msg, query)
+	expected := ex.(*ExpectedQueryRow)
+	var rows *Rows
+	if expected.row != nil {
+		rows = expected.row.rows
 	}
-	defer expected.Unlock()
-	if err := c.queryMatcher.Match(expected.expectSQL, query); err != nil {
-		return nil, fmt.Errorf("QueryRow: %v", err)
+	rows, err = cl.rows(expected.respond, rows)
+	if err != nil {
+		return &Row{err: err}
 	}
-
-	expected.triggered = true
-	return expected, expected.err
+	return &Row{rows: rows.withEmitter(newEmitter(cl.ctx, &expected.events))}
 }
 
 func (c *clickhousemock) ExpectQuery(expectedSQL string) *ExpectedQuery {
 	e := &ExpectedQuery{}
 	e.expectSQL = expectedSQL
-	c.expected = append(c.expected, e)
+	c.expect(e)
 	return e
 }
 
-// Query meets https://pkg.go.dev/github.com/ClickHouse/clickhouse-go/v2/lib/driver#Conn interface
-func (c *clickhousemock) Query(ctx context.Context, query string, args ...any) (driver.Rows, error) {
-	ex, err := c.query(ctx, query, args...)
-	if ex != nil {
-		select {
-		case <-time.After(ex.delay):
-			if err != nil {
-				return nil, err
-			}
-			return ex.rows, nil
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		}
-	}
-	return nil, err
+func (c *clickhousemock) ExpectBoundQuery(expectedSQL string) *ExpectedQuery {
+	return c.ExpectQuery(expectedSQL).MatchBound()
 }
 
-func (c *clickhousemock) query(ctx context.Context, query string, args ...any) (*ExpectedQuery, error) {
-	var expected *ExpectedQuery
-	var fulfilled int
-	var ok bool
-
-	for _, next := range c.expected {
-		next.Lock()
-		if next.fulfilled() {
-			next.Unlock()
-			fulfilled++
-			continue
-		}
-
-		if c.ordered {
-			if expected, ok = next.(*ExpectedQuery); ok {
-				break
-			}
-
-			next.Unlock()
-			return nil, fmt.Errorf("call to Query statement with query '%s', was not expected, next expectation is: %s", query, next)
-		}
+// Query meets https://pkg.go.dev/github.com/ClickHouse/clickhouse-go/v2/lib/driver#Conn interface
+func (c *clickhousemock) Query(ctx context.Context, query string, args ...any) (_ driver.Rows, err error) {
+	cl := newCall(ctx, "Query", query, args)
+	defer c.record(cl, &err)
 
-		if pr, ok := next.(*ExpectedQuery); ok {
-			if err := c.queryMatcher.Match(pr.expectSQL, query); err == nil {
-				expected = pr
-				break
-			}
-		}
-		next.Unlock()
+	if err = cl.bind(); err != nil {
+		return nil, err
 	}
 
-	if expected == nil {
-		msg := "call to Query '%s' query was not expected"
-		if fulfilled == len(c.expected) {
-			msg = "all expectations were already fulfilled, " + msg
-		}
-		return nil, fmt.Errorf(msg, query)
+	ex, err := c.match(cl)
+	if ex == nil {
+		return nil, err
 	}
-	defer expected.Unlock()
-	if err := c.queryMatcher.Match(expected.expectSQL, query); err != nil {
-		return nil, fmt.Errorf("Query: %v", err)
+	if werr := delayResult(ctx, ex); werr != nil {
+		return nil, werr
 	}
-
-	if err := expected.matchArgs(args); err != nil {
-		return nil, fmt.Errorf("Query: '%s' arguments do not match: %s", query, err)
+	if err != nil {
+		return nil, err
 	}
 
-	expected.triggered = true
-	return expected, expected.err
+	expected := ex.(*ExpectedQuery)
+	rows, err := cl.rows(expected.respond, expected.rows)
+	if err != nil {
+		return nil, err
+	}
+	rows = rows.withEmitter(newEmitter(ctx, &expected.events))
+	expected.Lock()
+	expected.rowsReturned++
+	expected.Unlock()
+	return &closeRows{Rows: rows, onClose: func() {
+		expected.Lock()
+		expected.rowsClosed++
+		expected.Unlock()
+	}}, nil
 }
 
 func (c *clickhousemock) ExpectSelect(expectedSQL string) *ExpectedSelect {
 	e := &ExpectedSelect{}
 	e.expectSQL = expectedSQL
-	c.expected = append(c.expected, e)
+	c.expect(e)
 	return e
 }
 
 // Select meets https://pkg.go.dev/github.com/ClickHouse/clickhouse-go/v2/lib/driver#Conn interface
-func (c *clickhousemock) Select(ctx context.Context, dest any, query string, args ...any) error {
+func (c *clickhousemock) Select(ctx context.Context, dest any, query string, args ...any) (err error) {
+	cl := newCall(ctx, "Select", query, args)
+	defer c.record(cl, &err)
+
+	if err = cl.bind(); err != nil {
+		return err
+	}
+
 	// Implementation based on that of Select in clickhouse-go https://github.com/ClickHouse/clickhouse-go/blob/main/scan.go#L29
 	dstSlicePtr := reflect.ValueOf(dest)
 	if dstSlicePtr.Kind() != reflect.Ptr {
@@ -665,185 +501,328 @@ func (c *clickhousemock) Select(ctx context.Context, dest any, query string, arg
 	}
 	dstSliceElType := dstSlice.Type().Elem()
 
-	ex, err := c.selectQuery(ctx, query)
-	if ex != nil {
-		select {
-		case <-time.After(ex.delay):
-			if err != nil {
-				return err
-			}
-
-			defer ex.rows.Close()
-			for ex.rows.Next() {
-				elem := reflect.New(dstSliceElType)
-				if err := ex.rows.ScanStruct(elem.Interface()); err != nil {
-					return err
-				}
-				dstSlice.Set(reflect.Append(dstSlice, elem.Elem()))
-			}
-			if err := ex.rows.Close(); err != nil {
-				return err
-			}
-			return ex.rows.Err()
-
-		case <-ctx.Done():
-			return ctx.Err()
-		}
+	ex, err := c.match(cl)
+	if ex == nil {
+		return err
 	}
-	return err
-}
-
-func (c *clickhousemock) selectQuery(ctx context.Context, query string) (*ExpectedSelect, error) {
-	var expected *ExpectedSelect
-	var fulfilled int
-	var ok bool
-
-	for _, next := range c.expected {
-		next.Lock()
-		if next.fulfilled() {
-			next.Unlock()
-			fulfilled++
-			continue
-		}
-
-		if c.ordered {
-			if expected, ok = next.(*ExpectedSelect); ok {
-				break
-			}
-
-			next.Unlock()
-			return nil, fmt.Errorf("call to Select statement with query '%s', was not expected, next expectation is: %s", query, next)
-		}
-
-		if pr, ok := next.(*ExpectedSelect); ok {
-			if err := c.queryMatcher.Match(pr.expectSQL, query); err == nil {
-				expected = pr
-				break
-			}
-		}
-		next.Unlock()
+	if werr := delayResult(ctx, ex); werr != nil {
+		return werr
+	}
+	if err != nil {
+		return err
 	}
 
-	if expected == nil {
-		msg := "call to Select '%s' query was not expected"
-		if fulfilled == len(c.expected) {
-			msg = "all expectations were already fulfilled, " + msg
+	expected := ex.(*ExpectedSelect)
+	rows, err := cl.rows(expected.respond, expected.rows)
+	if err != nil {
+		return err
+	}
+	rows = rows.withEmitter(newEmitter(ctx, &expected.events))
+	defer rows.Close()
+	for rows.Next() {
+		elem := reflect.New(dstSliceElType)
+		if err := rows.ScanStruct(elem.Interface()); err != nil {
+			return err
 		}
-		return nil, fmt.Errorf(msg, query)
+		dstSlice.Set(reflect.Append(dstSlice, elem.Elem()))
 	}
-	defer expected.Unlock()
-	if err := c.queryMatcher.Match(expected.expectSQL, query); err != nil {
-		return nil, fmt.Errorf("Select: %v", err)
+	if err := rows.Close(); err != nil {
+		return err
 	}
-
-	expected.triggered = true
-	return expected, expected.err
+	return rows.Err()
 }
 
 func (c *clickhousemock) ExpectServerVersion() *ExpectedServerVersion {
 	e := &ExpectedServerVersion{}
-	c.expected = append(c.expected, e)
+	c.expect(e)
 	return e
 }
 
 // ServerVersion returns the version of the database.
 // meets http://golang.org/pkg/database/sql/driver/#Conn interface
-func (c *clickhousemock) ServerVersion() (*driver.ServerVersion, error) {
-	c.drv.Lock()
-	defer c.drv.Unlock()
+func (c *clickhousemock) ServerVersion() (_ *driver.ServerVersion, err error) {
+	cl := newCall(context.Background(), "ServerVersion", "", nil)
+	defer c.record(cl, &err)
 
-	var expected *ExpectedServerVersion
-	var fulfilled int
-	var ok bool
-	for _, next := range c.expected {
+	ex, err := c.match(cl)
+	if ex == nil {
+		return &driver.ServerVersion{}, err
+	}
+	if werr := delayResult(context.Background(), ex); werr != nil {
+		return &driver.ServerVersion{}, werr
+	}
+	return &ex.(*ExpectedServerVersion).version, err
+}
 
-		if next.fulfilled() {
-			fulfilled++
-			continue
-		}
+func (c *clickhousemock) ExpectContributors() *ExpectedContributors {
+	e := &ExpectedContributors{}
+	c.expect(e)
+	return e
+}
 
-		if expected, ok = next.(*ExpectedServerVersion); ok {
-			break
-		}
+// Contributors meets https://pkg.go.dev/github.com/ClickHouse/clickhouse-go/v2/lib/driver#Conn interface
+func (c *clickhousemock) Contributors() []string {
+	var err error
+	cl := newCall(context.Background(), "Contributors", "", nil)
+	defer c.record(cl, &err)
 
-		if c.ordered {
-			return &driver.ServerVersion{}, fmt.Errorf("call to database ServerVersion, was not expected, next expectation is: %s", next)
+	ex, err := c.match(cl)
+	if ex == nil {
+		if c.t == nil {
+			panic(err)
 		}
+		return []string{}
 	}
+	delayResult(context.Background(), ex)
+	return ex.(*ExpectedContributors).contributors
+}
 
-	if expected == nil {
-		msg := "call to database ServerVersion was not expected"
-		if fulfilled == len(c.expected) {
-			msg = "all expectations were already fulfilled, " + msg
+// call describes a single invocation of a driver.Conn method
+// which is matched against the queued expectations.
+type call struct {
+	ctx    context.Context
+	method string
+	query  string
+	args   []any
+	start  time.Time
+
+	// bound is the query with the arguments rendered
+	// into it, as clickhouse-go would send it
+	bound string
+
+	// batch is set for calls made on a driver.Batch
+	batch bool
+	// async is set for calls sent as async inserts, wait is their wait flag
+	async bool
+	wait  bool
+	// ex is the expectation the call matched, if any
+	ex expectation
+	// n is the number of times the matched expectation was called,
+	// including this call
+	n int
+}
+
+func newCall(ctx context.Context, method, query string, args []any) *call {
+	return &call{ctx: ctx, method: method, query: query, args: args, bound: query, start: time.Now()}
+}
+
+// options returns the clickhouse.Context options of the call, including
+// the settings clickhouse-go adds to the ones of async inserts.
+func (cl *call) options() queryOptions {
+	opts := queryOptionsFromContext(cl.ctx)
+	if cl.async {
+		settings := make(clickhouse.Settings, len(opts.settings)+2)
+		for name, value := range opts.settings {
+			settings[name] = value
+		}
+		settings["async_insert"] = 1
+		settings["wait_for_async_insert"] = 0
+		if cl.wait {
+			settings["wait_for_async_insert"] = 1
 		}
-		return &driver.ServerVersion{}, fmt.Errorf(msg)
+		opts.settings = settings
 	}
+	return opts
+}
 
-	expected.triggered = true
-	return &expected.version, expected.err
+// is reports whether the call is one of the given method. Exec calls
+// made with clickhouse.WithAsync are AsyncInsert calls as well.
+func (cl *call) is(method string) bool {
+	return cl.method == method || cl.async && method == "AsyncInsert"
 }
 
-func (c *clickhousemock) ExpectContributors() *ExpectedContributors {
-	e := &ExpectedContributors{}
-	c.expected = append(c.expected, e)
-	return e
+func (cl *call) String() string {
+	switch cl.method {
+	case "Close", "Stats", "Ping", "ServerVersion", "Contributors":
+		return "database " + cl.method
+	}
+	return fmt.Sprintf("%s statement with query '%s'", cl.method, cl.query)
 }
 
-// Contributors meets https://pkg.go.dev/github.com/ClickHouse/clickhouse-go/v2/lib/driver#Conn interface
-func (c *clickhousemock) Contributors() []string {
-	c.drv.Lock()
-	defer c.drv.Unlock()
+// rows returns the rows the call results in, computed by respond
+// if set or the ones given to the expectation otherwise.
+func (cl *call) rows(respond Responder, rows *Rows) (*Rows, error) {
+	if respond != nil {
+		var err error
+		if rows, err = respond(cl.ctx, cl.query, cl.args); err != nil {
+			return nil, err
+		}
+	} else if rows != nil {
+		rows = rows.forCall(cl.n)
+	}
+	if rows == nil {
+		rows = NewRows(nil, nil)
+	}
+	return rows, nil
+}
+
+// match looks up the expectation satisfied by the given call and records
+// the call on it. Every driver.Conn method goes through it, so ordering,
+// SQL matching, argument matching and error wording are the same for all
+// of them. The returned error is either a matching failure, in which case
+// the expectation is nil, or the error the expectation was set to return.
+func (c *clickhousemock) match(cl *call) (expectation, error) {
+	c.expectedMu.Lock()
+	defer c.expectedMu.Unlock()
 
-	var expected *ExpectedContributors
+	var expected expectation
 	var fulfilled int
-	var ok bool
+	// orderErr is the reason the call did not match the next ungrouped
+	// expectation, groupErr is set if it matched an expectation of an
+	// InOrder group which was not due yet
+	var orderErr, groupErr error
+	// orderEx is the expectation orderErr was returned for
+	var orderEx expectation
 	for _, next := range c.expected {
+		g := next.common().group
+		var prev expectation
+		if g != nil && g.ordered {
+			prev = g.pending(next)
+		}
 
-		if next.fulfilled() {
+		next.Lock()
+		if next.exhausted() {
+			next.Unlock()
 			fulfilled++
 			continue
 		}
 
-		if expected, ok = next.(*ExpectedContributors); ok {
-			break
+		if prev != nil {
+			if groupErr == nil && c.matches(next, cl) == nil {
+				groupErr = fmt.Errorf("call to %s, was not expected yet, next expectation in its group is: %s", cl, prev)
+			}
+			next.Unlock()
+			continue
+		}
+
+		// ungrouped expectations are matched in order, one after
+		// another, unless MatchExpectationsInOrder(false) was set
+		inOrder := g == nil && c.ordered
+		if inOrder && orderErr != nil {
+			next.Unlock()
+			continue
 		}
 
-		if c.ordered {
-			return []string{}
+		err := c.matches(next, cl)
+		if err == nil {
+			expected = next
+			break
+		}
+		// an expectation which was called enough times
+		// may be passed over in favour of the next one
+		if inOrder && !next.fulfilled() {
+			orderErr, orderEx = err, next
 		}
+		next.Unlock()
 	}
 
 	if expected == nil {
-		return []string{}
+		err := groupErr
+		if err == nil {
+			err = orderErr
+		}
+		if err == nil {
+			msg := fmt.Sprintf("call to %s was not expected", cl)
+			if fulfilled == len(c.expected) {
+				msg = "all expectations were already fulfilled, " + msg
+			}
+			err = errors.New(msg)
+		}
+		if near := c.closest(cl); near != nil && !c.explained(near, orderEx, cl) {
+			if diag := c.diagnose(near, cl); diag != "" {
+				err = fmt.Errorf("%w\n%s", err, diag)
+			}
+		}
+		c.unexpected(err)
+		return nil, err
+	}
+
+	ce := expected.common()
+	ce.trigger()
+	cl.n = ce.calls
+	cl.ex = expected
+	expected.Unlock()
+	return expected, ce.err
+}
+
+// matches checks whether the call satisfies ex, it
+// has to be called with the expectation locked.
+func (c *clickhousemock) matches(ex expectation, cl *call) error {
+	if !cl.is(ex.method()) {
+		return fmt.Errorf("call to %s, was not expected, next expectation is: %s", cl, ex)
 	}
+	if err := ex.match(c.queryMatcher, cl); err != nil {
+		return fmt.Errorf("%s: %v", cl.method, err)
+	}
+	return nil
+}
 
-	expected.triggered = true
-	return expected.contributors
+// delayResult delays the result of a matched call for the duration configured
+// on its expectation, returning early if ctx is done.
+func delayResult(ctx context.Context, ex expectation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	delay := ex.common().delay
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (c *clickhousemock) ExpectationsWereMet() error {
+	c.expectedMu.Lock()
+	defer c.expectedMu.Unlock()
+
+	var unmet []UnmetExpectation
 	for _, e := range c.expected {
 		e.Lock()
-		fulfilled := e.fulfilled()
-		e.Unlock()
-
-		if !fulfilled {
-			return fmt.Errorf("there is a remaining expectation which was not matched: %s", e)
+		if !e.fulfilled() {
+			unmet = append(unmet, newUnmetExpectation(e, "", "not matched"))
 		}
 
-		// for expected prepared statement check whether it was closed if expected
-		if prep, ok := e.(*ExpectedPrepareBatch); ok {
-			if prep.mustBeSent && !prep.wasClosed {
-				return fmt.Errorf("expected prepared statement to be closed, but it was not: %s", prep)
+		switch e := e.(type) {
+		case *ExpectedPrepareBatch:
+			// for expected prepared statement check whether it was sent if expected
+			if e.calls > 0 && e.mustBeSent && !e.wasSent {
+				unmet = append(unmet, newUnmetExpectation(e, "", "batch was not sent"))
+			}
+			if e.calls > 0 && e.mustBeAborted && !e.wasAborted {
+				unmet = append(unmet, newUnmetExpectation(e, "", "batch was not aborted"))
+			}
+			if e.calls > 0 && e.expectRows && e.appendedRows != e.expectedRows {
+				unmet = append(unmet, newUnmetExpectation(e, "", fmt.Sprintf("batch has %d rows, expected %d", e.appendedRows, e.expectedRows)))
+			}
+		case *ExpectedQuery:
+			// must check whether all expected queried rows are closed
+			if e.rowsMustBeClosed && e.rowsClosed != e.rowsReturned {
+				reason := fmt.Sprintf("%d of %d returned rows were not closed", e.rowsReturned-e.rowsClosed, e.rowsReturned)
+				unmet = append(unmet, newUnmetExpectation(e, "", reason))
 			}
 		}
+		prepared := e.common().calls > 0
+		e.Unlock()
 
-		// must check whether all expected queried rows are closed
-		if query, ok := e.(*ExpectedQuery); ok {
-			if query.rowsMustBeClosed && !query.rowsWereClosed {
-				return fmt.Errorf("expected query rows to be closed, but it was not: %s", query)
+		// the steps of a batch which was never prepared could not be
+		// matched, the missing PrepareBatch is reported instead
+		if prep, ok := e.(*ExpectedPrepareBatch); ok && prepared {
+			for _, step := range prep.steps() {
+				step.Lock()
+				if !step.fulfilled() {
+					unmet = append(unmet, newUnmetExpectation(step, prep.expectSQL, "batch step not matched"))
+				}
+				step.Unlock()
 			}
 		}
 	}
-	return nil
+
+	if len(unmet) == 0 {
+		return nil
+	}
+	return &UnmetExpectationsError{Unmet: unmet}
 }
@@ -17,7 +17,11 @@ import (
 	"database/sql"
 	"errors"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
 )
 
 func TestPrepareExpectations(t *testing.T) {
@@ -413,6 +417,35 @@ func TestCorrectNumberOfCalls(t *testing.T) {
 	}
 }
 
+func TestConcurrentCallsMatchOnce(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	var wg sync.WaitGroup
+	var matched atomic.Int32
+	for i := 0; i < 20; i++ {
+		mock.ExpectExec("DELETE FROM articles").Times(1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := mock.Exec(context.Background(), "DELETE FROM articles"); err == nil {
+				matched.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := matched.Load(); n != 20 {
+		t.Errorf("expected 20 calls to match, got %d", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestArgumentMismatch(t *testing.T) {
 	t.Parallel()
 	mock, err := NewClickHouseNative(nil)
@@ -649,3 +682,150 @@ func TestQueryReturnRowCustomError(t *testing.T) {
 		t.Errorf("expected error to be some error, but got %s", err)
 	}
 }
+
+func TestExecUnorderedMatchesQuery(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.MatchExpectationsInOrder(false)
+
+	mock.ExpectExec("TRUNCATE TABLE articles")
+	mock.ExpectExec("OPTIMIZE TABLE articles FINAL")
+
+	if err := mock.Exec(context.Background(), "OPTIMIZE TABLE articles FINAL"); err != nil {
+		t.Errorf("an error '%s' was not expected when executing a statement", err)
+	}
+
+	if err := mock.Exec(context.Background(), "DROP TABLE articles"); err == nil {
+		t.Error("an error was expected due to unexpected query")
+	}
+
+	if err := mock.Exec(context.Background(), "TRUNCATE TABLE articles"); err != nil {
+		t.Errorf("an error '%s' was not expected when executing a statement", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestServerVersionAndContributors(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectServerVersion().WillReturnVersion(proto.ServerHandshake{Name: "ClickHouse"})
+	mock.ExpectContributors().WillReturnContributors("alice", "bob")
+
+	if _, err := mock.ServerVersion(); err != nil {
+		t.Errorf("an error '%s' was not expected when getting the server version", err)
+	}
+
+	if _, err := mock.ServerVersion(); err == nil {
+		t.Error("an error was expected due to ServerVersion called out of order")
+	}
+
+	if contributors := mock.Contributors(); len(contributors) != 2 {
+		t.Errorf("expected 2 contributors, but got %d", len(contributors))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUnexpectedCallErrorWording(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery("SELECT 1")
+
+	err = mock.Exec(context.Background(), "SELECT 1")
+	expected := "call to Exec statement with query 'SELECT 1', was not expected, next expectation is: " + mock.expected[0].String()
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error to be %q, but got %v", expected, err)
+	}
+
+	if _, err = mock.Query(context.Background(), "SELECT 1"); err != nil {
+		t.Errorf("an error '%s' was not expected when querying a statement", err)
+	}
+
+	err = mock.Close()
+	expected = "all expectations were already fulfilled, call to database Close was not expected"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error to be %q, but got %v", expected, err)
+	}
+}
+
+func TestArgumentMatchingOnEveryQueryMethod(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.MatchExpectationsInOrder(false)
+
+	mock.ExpectExec("ALTER TABLE articles DELETE WHERE id = ?").WithArgs(1)
+	mock.ExpectSelect("SELECT id, title FROM articles WHERE id = ?").WithArgs(1)
+	mock.ExpectQueryRow("SELECT id, title FROM articles WHERE id = ?").WithArgs(1)
+	mock.ExpectAsyncInsert("INSERT INTO articles VALUES (?, ?)", false).WithArgs(1, AnyArg())
+
+	var dest []struct {
+		ID    int32  `ch:"id"`
+		Title string `ch:"title"`
+	}
+	ctx := context.Background()
+	query := "ALTER TABLE articles DELETE WHERE id = ?"
+	if err := mock.Exec(ctx, query, 2); err == nil {
+		t.Error("an error was expected due to Exec argument mismatch")
+	}
+	if err := mock.Select(ctx, &dest, "SELECT id, title FROM articles WHERE id = ?", 2); err == nil {
+		t.Error("an error was expected due to Select argument mismatch")
+	}
+	if err := mock.QueryRow(ctx, "SELECT id, title FROM articles WHERE id = ?", 2).Err(); err == nil {
+		t.Error("an error was expected due to QueryRow argument mismatch")
+	}
+	if err := mock.AsyncInsert(ctx, "INSERT INTO articles VALUES (?, ?)", false, 2, "title"); err == nil {
+		t.Error("an error was expected due to AsyncInsert argument mismatch")
+	}
+
+	if err := mock.Exec(ctx, query, 1); err != nil {
+		t.Errorf("an error '%s' was not expected when executing a statement", err)
+	}
+	if err := mock.Select(ctx, &dest, "SELECT id, title FROM articles WHERE id = ?", 1); err != nil {
+		t.Errorf("an error '%s' was not expected when selecting", err)
+	}
+	if err := mock.QueryRow(ctx, "SELECT id, title FROM articles WHERE id = ?", 1).Err(); err != nil {
+		t.Errorf("an error '%s' was not expected when querying a row", err)
+	}
+	if err := mock.AsyncInsert(ctx, "INSERT INTO articles VALUES (?, ?)", false, 1, "title"); err != nil {
+		t.Errorf("an error '%s' was not expected when inserting", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestArgumentMismatchErrorFormat(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectExec("ALTER TABLE articles DELETE WHERE id = ?").WithArgs(1)
+
+	err = mock.Exec(context.Background(), "ALTER TABLE articles DELETE WHERE id = ?", 2)
+	expected := "Exec: 'ALTER TABLE articles DELETE WHERE id = ?' arguments do not match: argument 0: expected 1 (int), got 2 (int)"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error to be %q, but got %v", expected, err)
+	}
+}
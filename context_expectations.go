@@ -0,0 +1,342 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// contextExpectation holds the clickhouse.Context options an
+// expectation requires the context of its call to carry. Options
+// left nil are not checked.
+type contextExpectation struct {
+	settings     clickhouse.Settings
+	queryID      any
+	quotaKey     any
+	jwt          any
+	userLocation *time.Location
+	external     []externalTableExpectation
+}
+
+// match checks the options set on the context of cl with clickhouse.Context.
+func (e *contextExpectation) match(cl *call) error {
+	if e.settings == nil && e.queryID == nil && e.quotaKey == nil && e.jwt == nil && e.userLocation == nil && e.external == nil {
+		return nil
+	}
+	opts := cl.options()
+
+	names := make([]string, 0, len(e.settings))
+	for name := range e.settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, ok := opts.settings[name]
+		if !ok {
+			return fmt.Errorf("setting %q was not set", name)
+		}
+		if err := matchArg(e.settings[name], value); err != nil {
+			return fmt.Errorf("setting %q: %s", name, err)
+		}
+	}
+
+	if err := matchArg(e.queryID, opts.queryID); err != nil {
+		return fmt.Errorf("query ID: %s", err)
+	}
+	if err := matchArg(e.quotaKey, opts.quotaKey); err != nil {
+		return fmt.Errorf("quota key: %s", err)
+	}
+	if err := matchArg(e.jwt, opts.jwt); err != nil {
+		return fmt.Errorf("JWT: %s", err)
+	}
+	if e.userLocation != nil {
+		if opts.userLocation == nil {
+			return fmt.Errorf("user location: expected %s, got none", e.userLocation)
+		}
+		if opts.userLocation.String() != e.userLocation.String() {
+			return fmt.Errorf("user location: expected %s, got %s", e.userLocation, opts.userLocation)
+		}
+	}
+	if e.external != nil {
+		tables := externalTables(opts.external)
+		for i := range e.external {
+			if err := e.external[i].match(tables); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *ExpectedPing) match(_ QueryMatcher, cl *call) error {
+	if err := e.ctxOptions.match(cl); err != nil {
+		return fmt.Errorf("context options do not match: %s", err)
+	}
+	return nil
+}
+
+// WithSettings expects the context of the Query call to carry the given
+// settings, set with clickhouse.WithSettings. Other settings may be set as
+// well. Values may be Arguments.
+func (e *ExpectedQuery) WithSettings(settings clickhouse.Settings) *ExpectedQuery {
+	e.ctxOptions.settings = settings
+	return e
+}
+
+// WithQueryID expects the context of the Query call to carry the given query
+// ID, set with clickhouse.WithQueryID. The ID may be an Argument.
+func (e *ExpectedQuery) WithQueryID(id any) *ExpectedQuery {
+	e.ctxOptions.queryID = id
+	return e
+}
+
+// WithQuotaKey expects the context of the Query call to carry the given quota
+// key, set with clickhouse.WithQuotaKey. The key may be an Argument.
+func (e *ExpectedQuery) WithQuotaKey(key any) *ExpectedQuery {
+	e.ctxOptions.quotaKey = key
+	return e
+}
+
+// WithJWT expects the context of the Query call to carry the given JWT,
+// set with clickhouse.WithJWT. The token may be an Argument.
+func (e *ExpectedQuery) WithJWT(jwt any) *ExpectedQuery {
+	e.ctxOptions.jwt = jwt
+	return e
+}
+
+// WithUserLocation expects the context of the Query call to carry the given
+// location, set with clickhouse.WithUserLocation.
+func (e *ExpectedQuery) WithUserLocation(location *time.Location) *ExpectedQuery {
+	e.ctxOptions.userLocation = location
+	return e
+}
+
+// WithSettings expects the context of the Exec call to carry the given
+// settings, set with clickhouse.WithSettings. Other settings may be set as
+// well. Values may be Arguments.
+func (e *ExpectedExec) WithSettings(settings clickhouse.Settings) *ExpectedExec {
+	e.ctxOptions.settings = settings
+	return e
+}
+
+// WithQueryID expects the context of the Exec call to carry the given query
+// ID, set with clickhouse.WithQueryID. The ID may be an Argument.
+func (e *ExpectedExec) WithQueryID(id any) *ExpectedExec {
+	e.ctxOptions.queryID = id
+	return e
+}
+
+// WithQuotaKey expects the context of the Exec call to carry the given quota
+// key, set with clickhouse.WithQuotaKey. The key may be an Argument.
+func (e *ExpectedExec) WithQuotaKey(key any) *ExpectedExec {
+	e.ctxOptions.quotaKey = key
+	return e
+}
+
+// WithJWT expects the context of the Exec call to carry the given JWT,
+// set with clickhouse.WithJWT. The token may be an Argument.
+func (e *ExpectedExec) WithJWT(jwt any) *ExpectedExec {
+	e.ctxOptions.jwt = jwt
+	return e
+}
+
+// WithUserLocation expects the context of the Exec call to carry the given
+// location, set with clickhouse.WithUserLocation.
+func (e *ExpectedExec) WithUserLocation(location *time.Location) *ExpectedExec {
+	e.ctxOptions.userLocation = location
+	return e
+}
+
+// WithSettings expects the context of the Select call to carry the given
+// settings, set with clickhouse.WithSettings. Other settings may be set as
+// well. Values may be Arguments.
+func (e *ExpectedSelect) WithSettings(settings clickhouse.Settings) *ExpectedSelect {
+	e.ctxOptions.settings = settings
+	return e
+}
+
+// WithQueryID expects the context of the Select call to carry the given query
+// ID, set with clickhouse.WithQueryID. The ID may be an Argument.
+func (e *ExpectedSelect) WithQueryID(id any) *ExpectedSelect {
+	e.ctxOptions.queryID = id
+	return e
+}
+
+// WithQuotaKey expects the context of the Select call to carry the given quota
+// key, set with clickhouse.WithQuotaKey. The key may be an Argument.
+func (e *ExpectedSelect) WithQuotaKey(key any) *ExpectedSelect {
+	e.ctxOptions.quotaKey = key
+	return e
+}
+
+// WithJWT expects the context of the Select call to carry the given JWT,
+// set with clickhouse.WithJWT. The token may be an Argument.
+func (e *ExpectedSelect) WithJWT(jwt any) *ExpectedSelect {
+	e.ctxOptions.jwt = jwt
+	return e
+}
+
+// WithUserLocation expects the context of the Select call to carry the given
+// location, set with clickhouse.WithUserLocation.
+func (e *ExpectedSelect) WithUserLocation(location *time.Location) *ExpectedSelect {
+	e.ctxOptions.userLocation = location
+	return e
+}
+
+// WithSettings expects the context of the QueryRow call to carry the given
+// settings, set with clickhouse.WithSettings. Other settings may be set as
+// well. Values may be Arguments.
+func (e *ExpectedQueryRow) WithSettings(settings clickhouse.Settings) *ExpectedQueryRow {
+	e.ctxOptions.settings = settings
+	return e
+}
+
+// WithQueryID expects the context of the QueryRow call to carry the given query
+// ID, set with clickhouse.WithQueryID. The ID may be an Argument.
+func (e *ExpectedQueryRow) WithQueryID(id any) *ExpectedQueryRow {
+	e.ctxOptions.queryID = id
+	return e
+}
+
+// WithQuotaKey expects the context of the QueryRow call to carry the given quota
+// key, set with clickhouse.WithQuotaKey. The key may be an Argument.
+func (e *ExpectedQueryRow) WithQuotaKey(key any) *ExpectedQueryRow {
+	e.ctxOptions.quotaKey = key
+	return e
+}
+
+// WithJWT expects the context of the QueryRow call to carry the given JWT,
+// set with clickhouse.WithJWT. The token may be an Argument.
+func (e *ExpectedQueryRow) WithJWT(jwt any) *ExpectedQueryRow {
+	e.ctxOptions.jwt = jwt
+	return e
+}
+
+// WithUserLocation expects the context of the QueryRow call to carry the given
+// location, set with clickhouse.WithUserLocation.
+func (e *ExpectedQueryRow) WithUserLocation(location *time.Location) *ExpectedQueryRow {
+	e.ctxOptions.userLocation = location
+	return e
+}
+
+// WithSettings expects the context of the AsyncInsert call to carry the given
+// settings, set with clickhouse.WithSettings. Other settings may be set as
+// well. Values may be Arguments.
+func (e *ExpectedAsyncInsert) WithSettings(settings clickhouse.Settings) *ExpectedAsyncInsert {
+	e.ctxOptions.settings = settings
+	return e
+}
+
+// WithQueryID expects the context of the AsyncInsert call to carry the given query
+// ID, set with clickhouse.WithQueryID. The ID may be an Argument.
+func (e *ExpectedAsyncInsert) WithQueryID(id any) *ExpectedAsyncInsert {
+	e.ctxOptions.queryID = id
+	return e
+}
+
+// WithQuotaKey expects the context of the AsyncInsert call to carry the given quota
+// key, set with clickhouse.WithQuotaKey. The key may be an Argument.
+func (e *ExpectedAsyncInsert) WithQuotaKey(key any) *ExpectedAsyncInsert {
+	e.ctxOptions.quotaKey = key
+	return e
+}
+
+// WithJWT expects the context of the AsyncInsert call to carry the given JWT,
+// set with clickhouse.WithJWT. The token may be an Argument.
+func (e *ExpectedAsyncInsert) WithJWT(jwt any) *ExpectedAsyncInsert {
+	e.ctxOptions.jwt = jwt
+	return e
+}
+
+// WithUserLocation expects the context of the AsyncInsert call to carry the given
+// location, set with clickhouse.WithUserLocation.
+func (e *ExpectedAsyncInsert) WithUserLocation(location *time.Location) *ExpectedAsyncInsert {
+	e.ctxOptions.userLocation = location
+	return e
+}
+
+// WithSettings expects the context of the PrepareBatch call to carry the given
+// settings, set with clickhouse.WithSettings. Other settings may be set as
+// well. Values may be Arguments.
+func (e *ExpectedPrepareBatch) WithSettings(settings clickhouse.Settings) *ExpectedPrepareBatch {
+	e.ctxOptions.settings = settings
+	return e
+}
+
+// WithQueryID expects the context of the PrepareBatch call to carry the given query
+// ID, set with clickhouse.WithQueryID. The ID may be an Argument.
+func (e *ExpectedPrepareBatch) WithQueryID(id any) *ExpectedPrepareBatch {
+	e.ctxOptions.queryID = id
+	return e
+}
+
+// WithQuotaKey expects the context of the PrepareBatch call to carry the given quota
+// key, set with clickhouse.WithQuotaKey. The key may be an Argument.
+func (e *ExpectedPrepareBatch) WithQuotaKey(key any) *ExpectedPrepareBatch {
+	e.ctxOptions.quotaKey = key
+	return e
+}
+
+// WithJWT expects the context of the PrepareBatch call to carry the given JWT,
+// set with clickhouse.WithJWT. The token may be an Argument.
+func (e *ExpectedPrepareBatch) WithJWT(jwt any) *ExpectedPrepareBatch {
+	e.ctxOptions.jwt = jwt
+	return e
+}
+
+// WithUserLocation expects the context of the PrepareBatch call to carry the given
+// location, set with clickhouse.WithUserLocation.
+func (e *ExpectedPrepareBatch) WithUserLocation(location *time.Location) *ExpectedPrepareBatch {
+	e.ctxOptions.userLocation = location
+	return e
+}
+
+// WithSettings expects the context of the Ping call to carry the given
+// settings, set with clickhouse.WithSettings. Other settings may be set as
+// well. Values may be Arguments.
+func (e *ExpectedPing) WithSettings(settings clickhouse.Settings) *ExpectedPing {
+	e.ctxOptions.settings = settings
+	return e
+}
+
+// WithQueryID expects the context of the Ping call to carry the given query
+// ID, set with clickhouse.WithQueryID. The ID may be an Argument.
+func (e *ExpectedPing) WithQueryID(id any) *ExpectedPing {
+	e.ctxOptions.queryID = id
+	return e
+}
+
+// WithQuotaKey expects the context of the Ping call to carry the given quota
+// key, set with clickhouse.WithQuotaKey. The key may be an Argument.
+func (e *ExpectedPing) WithQuotaKey(key any) *ExpectedPing {
+	e.ctxOptions.quotaKey = key
+	return e
+}
+
+// WithJWT expects the context of the Ping call to carry the given JWT,
+// set with clickhouse.WithJWT. The token may be an Argument.
+func (e *ExpectedPing) WithJWT(jwt any) *ExpectedPing {
+	e.ctxOptions.jwt = jwt
+	return e
+}
+
+// WithUserLocation expects the context of the Ping call to carry the given
+// location, set with clickhouse.WithUserLocation.
+func (e *ExpectedPing) WithUserLocation(location *time.Location) *ExpectedPing {
+	e.ctxOptions.userLocation = location
+	return e
+}
@@ -0,0 +1,123 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextExpectations(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery("SELECT id FROM articles").
+		WithSettings(clickhouse.Settings{"max_threads": 2, "readonly": AnyArg()}).
+		WithQueryID(Regexp("^tenant-42-")).
+		WithQuotaKey("tenant-42").
+		WithUserLocation(time.UTC)
+	mock.ExpectExec("TRUNCATE TABLE articles").WithJWT("token")
+
+	ctx := clickhouse.Context(context.Background(),
+		clickhouse.WithSettings(clickhouse.Settings{"max_threads": 2, "readonly": 1, "max_memory_usage": 1000}),
+		clickhouse.WithQueryID("tenant-42-1f2e"),
+		clickhouse.WithQuotaKey("tenant-42"),
+		clickhouse.WithUserLocation(time.UTC),
+	)
+	_, err = mock.Query(ctx, "SELECT id FROM articles")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.Exec(clickhouse.Context(context.Background(), clickhouse.WithJWT("token")), "TRUNCATE TABLE articles"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContextExpectationMismatches(t *testing.T) {
+	t.Parallel()
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("timezone database is not available")
+	}
+
+	for _, tc := range []struct {
+		name   string
+		expect func(*ExpectedExec)
+		ctx    context.Context
+		err    string
+	}{
+		{
+			name:   "missing setting",
+			expect: func(e *ExpectedExec) { e.WithSettings(clickhouse.Settings{"max_threads": 2}) },
+			ctx:    context.Background(),
+			err:    `setting "max_threads" was not set`,
+		},
+		{
+			name:   "setting value",
+			expect: func(e *ExpectedExec) { e.WithSettings(clickhouse.Settings{"max_threads": 2}) },
+			ctx:    clickhouse.Context(context.Background(), clickhouse.WithSettings(clickhouse.Settings{"max_threads": 4})),
+			err:    `setting "max_threads": expected 2 (int), got 4 (int)`,
+		},
+		{
+			name:   "query ID",
+			expect: func(e *ExpectedExec) { e.WithQueryID("a") },
+			ctx:    clickhouse.Context(context.Background(), clickhouse.WithQueryID("b")),
+			err:    "query ID: expected a (string), got b (string)",
+		},
+		{
+			name:   "quota key",
+			expect: func(e *ExpectedExec) { e.WithQuotaKey("a") },
+			ctx:    context.Background(),
+			err:    "quota key: expected a (string), got  (string)",
+		},
+		{
+			name:   "user location",
+			expect: func(e *ExpectedExec) { e.WithUserLocation(time.UTC) },
+			ctx:    clickhouse.Context(context.Background(), clickhouse.WithUserLocation(berlin)),
+			err:    "user location: expected UTC, got Europe/Berlin",
+		},
+	} {
+		mock, err := NewClickHouseNative(nil)
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		tc.expect(mock.ExpectExec("TRUNCATE TABLE articles"))
+
+		err = mock.Exec(tc.ctx, "TRUNCATE TABLE articles")
+		assert.EqualError(t, err, "Exec: 'TRUNCATE TABLE articles' context options do not match: "+tc.err, tc.name)
+	}
+}
+
+func TestContextExpectationsOnBatchAndPing(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.monitorPings = true
+
+	mock.ExpectPing().WithQuotaKey("tenant-42")
+	mock.ExpectPrepareBatch("INSERT INTO articles").WithSettings(clickhouse.Settings{"insert_deduplicate": 0})
+
+	assert.EqualError(t, mock.Ping(context.Background()), `Ping: context options do not match: quota key: expected tenant-42 (string), got  (string)`)
+	assert.NoError(t, mock.Ping(clickhouse.Context(context.Background(), clickhouse.WithQuotaKey("tenant-42"))))
+
+	ctx := clickhouse.Context(context.Background(), clickhouse.WithSettings(clickhouse.Settings{"insert_deduplicate": 0}))
+	_, err = mock.PrepareBatch(ctx, "INSERT INTO articles")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
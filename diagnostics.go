@@ -0,0 +1,220 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged tokens kept
+// around every change of a query diff.
+const diffContext = 3
+
+// queryExpectation is implemented by the expectations
+// embedding a queryBasedExpectation.
+type queryExpectation interface {
+	expectation
+	query() *queryBasedExpectation
+}
+
+func (e *queryBasedExpectation) query() *queryBasedExpectation {
+	return e
+}
+
+// closest returns the pending expectation of the called method whose
+// query and arguments are the nearest to the ones of cl, if any.
+func (c *clickhousemock) closest(cl *call) queryExpectation {
+	if cl.query == "" {
+		return nil
+	}
+	var closest queryExpectation
+	best := -1
+	for _, next := range c.expected {
+		ex, ok := next.(queryExpectation)
+		if !ok || !cl.is(ex.method()) {
+			continue
+		}
+		ex.Lock()
+		if !ex.exhausted() {
+			q := ex.query()
+			actual := tokenTexts(tokenize(q.actualSQL(cl)))
+			score := tokenDistance(tokenTexts(tokenize(q.expectSQL)), actual)
+			score += len(argumentDiff(q.args, cl.args))
+			if best < 0 || score < best {
+				closest, best = ex, score
+			}
+		}
+		ex.Unlock()
+	}
+	return closest
+}
+
+// explained tells whether the error the call failed with already explains
+// why it does not match near, which is the case when near is the
+// expectation it was compared to in order and its query matched.
+func (c *clickhousemock) explained(near queryExpectation, orderEx expectation, cl *call) bool {
+	if near != orderEx {
+		return false
+	}
+	near.Lock()
+	defer near.Unlock()
+	q := near.query()
+	return q.queryMatcher(c.queryMatcher).Match(q.expectSQL, q.actualSQL(cl)) == nil
+}
+
+// diagnose explains how cl differs from the expectation ex. It returns
+// an empty string if neither the query nor the arguments differ.
+func (c *clickhousemock) diagnose(ex queryExpectation, cl *call) string {
+	ex.Lock()
+	defer ex.Unlock()
+
+	var lines []string
+	q := ex.query()
+	if actual := q.actualSQL(cl); q.queryMatcher(c.queryMatcher).Match(q.expectSQL, actual) != nil {
+		lines = append(lines, "query diff: "+queryDiff(q.expectSQL, actual))
+	}
+	lines = append(lines, argumentDiff(q.args, cl.args)...)
+	if len(lines) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("closest expectation is: %s\n  %s", ex, strings.Join(lines, "\n  "))
+}
+
+// argumentDiff describes every argument which does not match its expected
+// value, with the values and Go types on both sides.
+func argumentDiff(expected, actual []any) []string {
+	if expected == nil {
+		return nil
+	}
+	var diff []string
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		switch {
+		case i >= len(actual):
+			diff = append(diff, fmt.Sprintf("argument %d: expected %v (%T), got nothing", i, expected[i], expected[i]))
+		case i >= len(expected):
+			diff = append(diff, fmt.Sprintf("argument %d: not expected, got %v (%T)", i, actual[i], actual[i]))
+		default:
+			if err := matchArg(expected[i], actual[i]); err != nil {
+				diff = append(diff, fmt.Sprintf("argument %d: %s", i, err))
+			}
+		}
+	}
+	return diff
+}
+
+// queryDiff renders a token level diff of two queries, marking the tokens
+// only the expected query has as [-removed-] and the ones only the actual
+// query has as {+added+}. Long runs of unchanged tokens are elided.
+func queryDiff(expected, actual string) string {
+	a, b := tokenTexts(tokenize(expected)), tokenTexts(tokenize(actual))
+	lcs := lcsTable(a, b)
+
+	type op struct {
+		kind byte // ' ', '-' or '+'
+		text string
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, op{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', b[j]})
+			j++
+		}
+	}
+
+	var out []string
+	for k := 0; k < len(ops); {
+		end := k
+		for end < len(ops) && ops[end].kind == ops[k].kind {
+			end++
+		}
+		texts := make([]string, 0, end-k)
+		for _, o := range ops[k:end] {
+			texts = append(texts, o.text)
+		}
+		switch ops[k].kind {
+		case '-':
+			out = append(out, "[-"+strings.Join(texts, " ")+"-]")
+		case '+':
+			out = append(out, "{+"+strings.Join(texts, " ")+"+}")
+		default:
+			out = append(out, elide(texts, k == 0, end == len(ops))...)
+		}
+		k = end
+	}
+	return strings.Join(out, " ")
+}
+
+// elide shortens a run of unchanged tokens to the ones next
+// to a change, first and last tell whether the run starts or
+// ends the query.
+func elide(texts []string, first, last bool) []string {
+	keep := 2 * diffContext
+	if first || last {
+		keep = diffContext
+	}
+	if len(texts) <= keep+1 {
+		return texts
+	}
+	switch {
+	case first && last:
+		return texts
+	case first:
+		return append([]string{"..."}, texts[len(texts)-diffContext:]...)
+	case last:
+		return append(texts[:diffContext:diffContext], "...")
+	}
+	return append(append(texts[:diffContext:diffContext], "..."), texts[len(texts)-diffContext:]...)
+}
+
+func tokenTexts(tokens []token) []string {
+	texts := make([]string, len(tokens))
+	for i, t := range tokens {
+		texts[i] = t.text
+	}
+	return texts
+}
+
+// lcsTable returns the lengths of the longest common subsequences
+// of every pair of suffixes of a and b.
+func lcsTable(a, b []string) [][]int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return lcs
+}
+
+// tokenDistance returns the number of tokens to remove
+// and to add to turn the tokens a into b.
+func tokenDistance(a, b []string) int {
+	return len(a) + len(b) - 2*lcsTable(a, b)[0][0]
+}
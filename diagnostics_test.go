@@ -0,0 +1,83 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnexpectedCallShowsClosestExpectation(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.MatchExpectationsInOrder(false)
+
+	mock.ExpectExec("TRUNCATE TABLE jobs")
+	mock.ExpectQuery("SELECT id, title FROM articles WHERE id = ? AND status = ?").WithArgs(1, "draft")
+	mock.ExpectQuery("SELECT count() FROM jobs")
+
+	err = mock.Exec(context.Background(), "TRUNCATE TABLE articles")
+	assert.EqualError(t, err, "call to Exec statement with query 'TRUNCATE TABLE articles' was not expected\n"+
+		"closest expectation is: "+mock.expected[0].String()+"\n"+
+		"  query diff: TRUNCATE TABLE [-jobs-] {+articles+}")
+
+	_, err = mock.Query(context.Background(), "SELECT id, name FROM articles WHERE id = ? AND status = ?", int64(1), "draft", true)
+	assert.EqualError(t, err, "call to Query statement with query 'SELECT id, name FROM articles WHERE id = ? AND status = ?' was not expected\n"+
+		"closest expectation is: "+mock.expected[1].String()+"\n"+
+		"  query diff: SELECT id , [-title-] {+name+} FROM articles WHERE ...\n"+
+		"  argument 0: expected 1 (int), got 1 (int64)\n"+
+		"  argument 2: not expected, got true (bool)")
+}
+
+func TestUnexpectedCallWithoutCandidate(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.MatchExpectationsInOrder(false)
+
+	mock.ExpectExec("TRUNCATE TABLE jobs")
+
+	_, err = mock.Query(context.Background(), "SELECT 1")
+	assert.EqualError(t, err, "call to Query statement with query 'SELECT 1' was not expected")
+}
+
+func TestQueryDiff(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		expected, actual, diff string
+	}{
+		{"SELECT 1", "SELECT 1", "SELECT 1"},
+		{"SELECT 1", "SELECT 2", "SELECT [-1-] {+2+}"},
+		{"SELECT a FROM t", "SELECT a, b FROM t", "SELECT a {+, b+} FROM t"},
+		{"SELECT 'a b' FROM t", "SELECT 'a  b' FROM t", "SELECT [-'a b'-] {+'a  b'+} FROM t"},
+		{
+			"SELECT a, b, c, d, e FROM t WHERE x = 1 AND y = 2 ORDER BY a",
+			"SELECT a, b, c, d, e FROM t WHERE x = 1 AND y = 3 ORDER BY a",
+			"... AND y = [-2-] {+3+} ORDER BY a",
+		},
+		{
+			"SELECT a FROM t WHERE x = 1 AND y = 2 AND z = 3 AND w = 4",
+			"SELECT b FROM t WHERE x = 1 AND y = 2 AND z = 3 AND w = 5",
+			"SELECT [-a-] {+b+} FROM t WHERE ... AND w = [-4-] {+5+}",
+		},
+	} {
+		assert.Equal(t, tc.diff, queryDiff(tc.expected, tc.actual), "diff of %q and %q", tc.expected, tc.actual)
+	}
+}
@@ -17,7 +17,6 @@ import (
 	"sync"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
-	"github.com/DATA-DOG/go-sqlmock"
 )
 
 var clickHousePool *mockClickHouseDriver
@@ -36,12 +35,14 @@ func init() {
 
 // NewClickHouseNative creates clickhousemock database mock to manage expectations.
 func NewClickHouseNative(options *clickhouse.Options) (*clickhousemock, error) {
-	return NewClickHouseWithQueryMatcher(options, sqlmock.QueryMatcherEqual)
+	return NewClickHouseWithQueryMatcher(options, MatchEqual)
 }
 
+// NewClickHouseWithQueryMatcher creates clickhousemock database mock matching
+// the SQL of calls against the one of expectations with queryMatcher.
 func NewClickHouseWithQueryMatcher(
 	options *clickhouse.Options,
-	queryMatcher sqlmock.QueryMatcher,
+	queryMatcher QueryMatcher,
 ) (*clickhousemock, error) {
 	clickHousePool.Lock()
 	dsn := fmt.Sprintf("clickhousemock_db_%d", clickHousePool.counter)
@@ -0,0 +1,302 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// events holds the server events an expectation emits to the callbacks
+// registered on the context of its call.
+type events struct {
+	logs          []clickhouse.Log
+	progress      []clickhouse.Progress
+	profileInfo   []clickhouse.ProfileInfo
+	profileEvents [][]clickhouse.ProfileEvent
+}
+
+func (e *events) empty() bool {
+	return len(e.logs) == 0 && len(e.progress) == 0 && len(e.profileInfo) == 0 && len(e.profileEvents) == 0
+}
+
+// emitter emits events to the callbacks of a call in the order the server
+// sends them: logs as the query starts, a progress packet with every row
+// read, and the remaining progress packets, profile info and profile
+// events once the result is exhausted.
+type emitter struct {
+	events  *events
+	opts    queryOptions
+	started bool
+	done    bool
+	// rows is the number of rows progress was emitted for
+	rows     int
+	progress int
+}
+
+// newEmitter returns an emitter for the events of an expectation, or nil
+// if there are none or the context of the call has no callbacks for them.
+func newEmitter(ctx context.Context, e *events) *emitter {
+	if e.empty() {
+		return nil
+	}
+	opts := queryOptionsFromContext(ctx)
+	if opts.logs == nil && opts.progress == nil && opts.profileInfo == nil && opts.profileEvents == nil {
+		return nil
+	}
+	return &emitter{events: e, opts: opts}
+}
+
+// start emits the logs.
+func (m *emitter) start() {
+	if m.started {
+		return
+	}
+	m.started = true
+	if m.opts.logs != nil {
+		for _, l := range m.events.logs {
+			m.opts.logs(&l)
+		}
+	}
+}
+
+// next is called by every Rows.Next with the position of the row it is
+// about to return, and whether there is one. Progress is emitted once
+// per row, the remaining events before the end of the result.
+func (m *emitter) next(pos int, more bool) {
+	m.start()
+	if !more {
+		m.end()
+		return
+	}
+	if pos < m.rows {
+		return
+	}
+	m.rows = pos + 1
+	if m.progress < len(m.events.progress) {
+		m.emitProgress(m.events.progress[m.progress])
+		m.progress++
+	}
+}
+
+// end emits all events which were not emitted yet.
+func (m *emitter) end() {
+	m.start()
+	if m.done {
+		return
+	}
+	m.done = true
+	for ; m.progress < len(m.events.progress); m.progress++ {
+		m.emitProgress(m.events.progress[m.progress])
+	}
+	if m.opts.profileInfo != nil {
+		for _, p := range m.events.profileInfo {
+			m.opts.profileInfo(&p)
+		}
+	}
+	if m.opts.profileEvents != nil {
+		for _, e := range m.events.profileEvents {
+			m.opts.profileEvents(e)
+		}
+	}
+}
+
+func (m *emitter) emitProgress(p clickhouse.Progress) {
+	if m.opts.progress != nil {
+		m.opts.progress(&p)
+	}
+}
+
+// emit emits all events of e to the callbacks of ctx at once,
+// as calls returning no rows receive them.
+func (e *events) emit(ctx context.Context) {
+	if m := newEmitter(ctx, e); m != nil {
+		m.end()
+	}
+}
+
+func progressValues(progress []*clickhouse.Progress) []clickhouse.Progress {
+	values := make([]clickhouse.Progress, 0, len(progress))
+	for _, p := range progress {
+		if p != nil {
+			values = append(values, *p)
+		}
+	}
+	return values
+}
+
+func profileInfoValues(info []*clickhouse.ProfileInfo) []clickhouse.ProfileInfo {
+	values := make([]clickhouse.ProfileInfo, 0, len(info))
+	for _, p := range info {
+		if p != nil {
+			values = append(values, *p)
+		}
+	}
+	return values
+}
+
+func logValues(logs []*clickhouse.Log) []clickhouse.Log {
+	values := make([]clickhouse.Log, 0, len(logs))
+	for _, l := range logs {
+		if l != nil {
+			values = append(values, *l)
+		}
+	}
+	return values
+}
+
+// WillEmitProgress emits the given progress packets to the callback registered
+// with clickhouse.WithProgress on the context of the Query call, while the returned rows are iterated.
+func (e *ExpectedQuery) WillEmitProgress(progress ...*clickhouse.Progress) *ExpectedQuery {
+	e.events.progress = append(e.events.progress, progressValues(progress)...)
+	return e
+}
+
+// WillEmitProfileInfo emits the given profile info to the callback registered
+// with clickhouse.WithProfileInfo on the context of the Query call, while the returned rows are iterated.
+func (e *ExpectedQuery) WillEmitProfileInfo(info ...*clickhouse.ProfileInfo) *ExpectedQuery {
+	e.events.profileInfo = append(e.events.profileInfo, profileInfoValues(info)...)
+	return e
+}
+
+// WillEmitProfileEvents emits the given profile events as one packet to the
+// callback registered with clickhouse.WithProfileEvents on the context of the
+// Query call, while the returned rows are iterated. It may be used several times to emit several packets.
+func (e *ExpectedQuery) WillEmitProfileEvents(events ...clickhouse.ProfileEvent) *ExpectedQuery {
+	e.events.profileEvents = append(e.events.profileEvents, events)
+	return e
+}
+
+// WillEmitLogs emits the given server logs to the callback registered with
+// clickhouse.WithLogs on the context of the Query call, while the returned rows are iterated.
+func (e *ExpectedQuery) WillEmitLogs(logs ...*clickhouse.Log) *ExpectedQuery {
+	e.events.logs = append(e.events.logs, logValues(logs)...)
+	return e
+}
+
+// WillEmitProgress emits the given progress packets to the callback registered
+// with clickhouse.WithProgress on the context of the QueryRow call, when the row is scanned.
+func (e *ExpectedQueryRow) WillEmitProgress(progress ...*clickhouse.Progress) *ExpectedQueryRow {
+	e.events.progress = append(e.events.progress, progressValues(progress)...)
+	return e
+}
+
+// WillEmitProfileInfo emits the given profile info to the callback registered
+// with clickhouse.WithProfileInfo on the context of the QueryRow call, when the row is scanned.
+func (e *ExpectedQueryRow) WillEmitProfileInfo(info ...*clickhouse.ProfileInfo) *ExpectedQueryRow {
+	e.events.profileInfo = append(e.events.profileInfo, profileInfoValues(info)...)
+	return e
+}
+
+// WillEmitProfileEvents emits the given profile events as one packet to the
+// callback registered with clickhouse.WithProfileEvents on the context of the
+// QueryRow call, when the row is scanned. It may be used several times to emit several packets.
+func (e *ExpectedQueryRow) WillEmitProfileEvents(events ...clickhouse.ProfileEvent) *ExpectedQueryRow {
+	e.events.profileEvents = append(e.events.profileEvents, events)
+	return e
+}
+
+// WillEmitLogs emits the given server logs to the callback registered with
+// clickhouse.WithLogs on the context of the QueryRow call, when the row is scanned.
+func (e *ExpectedQueryRow) WillEmitLogs(logs ...*clickhouse.Log) *ExpectedQueryRow {
+	e.events.logs = append(e.events.logs, logValues(logs)...)
+	return e
+}
+
+// WillEmitProgress emits the given progress packets to the callback registered
+// with clickhouse.WithProgress on the context of the Select call, while the rows are scanned into the destination.
+func (e *ExpectedSelect) WillEmitProgress(progress ...*clickhouse.Progress) *ExpectedSelect {
+	e.events.progress = append(e.events.progress, progressValues(progress)...)
+	return e
+}
+
+// WillEmitProfileInfo emits the given profile info to the callback registered
+// with clickhouse.WithProfileInfo on the context of the Select call, while the rows are scanned into the destination.
+func (e *ExpectedSelect) WillEmitProfileInfo(info ...*clickhouse.ProfileInfo) *ExpectedSelect {
+	e.events.profileInfo = append(e.events.profileInfo, profileInfoValues(info)...)
+	return e
+}
+
+// WillEmitProfileEvents emits the given profile events as one packet to the
+// callback registered with clickhouse.WithProfileEvents on the context of the
+// Select call, while the rows are scanned into the destination. It may be used several times to emit several packets.
+func (e *ExpectedSelect) WillEmitProfileEvents(events ...clickhouse.ProfileEvent) *ExpectedSelect {
+	e.events.profileEvents = append(e.events.profileEvents, events)
+	return e
+}
+
+// WillEmitLogs emits the given server logs to the callback registered with
+// clickhouse.WithLogs on the context of the Select call, while the rows are scanned into the destination.
+func (e *ExpectedSelect) WillEmitLogs(logs ...*clickhouse.Log) *ExpectedSelect {
+	e.events.logs = append(e.events.logs, logValues(logs)...)
+	return e
+}
+
+// WillEmitProgress emits the given progress packets to the callback registered
+// with clickhouse.WithProgress on the context of the Exec call, before the call returns.
+func (e *ExpectedExec) WillEmitProgress(progress ...*clickhouse.Progress) *ExpectedExec {
+	e.events.progress = append(e.events.progress, progressValues(progress)...)
+	return e
+}
+
+// WillEmitProfileInfo emits the given profile info to the callback registered
+// with clickhouse.WithProfileInfo on the context of the Exec call, before the call returns.
+func (e *ExpectedExec) WillEmitProfileInfo(info ...*clickhouse.ProfileInfo) *ExpectedExec {
+	e.events.profileInfo = append(e.events.profileInfo, profileInfoValues(info)...)
+	return e
+}
+
+// WillEmitProfileEvents emits the given profile events as one packet to the
+// callback registered with clickhouse.WithProfileEvents on the context of the
+// Exec call, before the call returns. It may be used several times to emit several packets.
+func (e *ExpectedExec) WillEmitProfileEvents(events ...clickhouse.ProfileEvent) *ExpectedExec {
+	e.events.profileEvents = append(e.events.profileEvents, events)
+	return e
+}
+
+// WillEmitLogs emits the given server logs to the callback registered with
+// clickhouse.WithLogs on the context of the Exec call, before the call returns.
+func (e *ExpectedExec) WillEmitLogs(logs ...*clickhouse.Log) *ExpectedExec {
+	e.events.logs = append(e.events.logs, logValues(logs)...)
+	return e
+}
+
+// WillEmitProgress emits the given progress packets to the callback registered
+// with clickhouse.WithProgress on the context of the batch call, when the batch is sent.
+func (e *ExpectedPrepareBatch) WillEmitProgress(progress ...*clickhouse.Progress) *ExpectedPrepareBatch {
+	e.events.progress = append(e.events.progress, progressValues(progress)...)
+	return e
+}
+
+// WillEmitProfileInfo emits the given profile info to the callback registered
+// with clickhouse.WithProfileInfo on the context of the batch call, when the batch is sent.
+func (e *ExpectedPrepareBatch) WillEmitProfileInfo(info ...*clickhouse.ProfileInfo) *ExpectedPrepareBatch {
+	e.events.profileInfo = append(e.events.profileInfo, profileInfoValues(info)...)
+	return e
+}
+
+// WillEmitProfileEvents emits the given profile events as one packet to the
+// callback registered with clickhouse.WithProfileEvents on the context of the
+// batch call, when the batch is sent. It may be used several times to emit several packets.
+func (e *ExpectedPrepareBatch) WillEmitProfileEvents(events ...clickhouse.ProfileEvent) *ExpectedPrepareBatch {
+	e.events.profileEvents = append(e.events.profileEvents, events)
+	return e
+}
+
+// WillEmitLogs emits the given server logs to the callback registered with
+// clickhouse.WithLogs on the context of the batch call, when the batch is sent.
+func (e *ExpectedPrepareBatch) WillEmitLogs(logs ...*clickhouse.Log) *ExpectedPrepareBatch {
+	e.events.logs = append(e.events.logs, logValues(logs)...)
+	return e
+}
@@ -0,0 +1,153 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// eventLog records the events emitted to the callbacks of its context.
+type eventLog []string
+
+func (l *eventLog) context() context.Context {
+	return clickhouse.Context(context.Background(),
+		clickhouse.WithLogs(func(log *clickhouse.Log) {
+			*l = append(*l, "log "+log.Text)
+		}),
+		clickhouse.WithProgress(func(p *clickhouse.Progress) {
+			*l = append(*l, fmt.Sprintf("progress %d", p.Rows))
+		}),
+		clickhouse.WithProfileInfo(func(p *clickhouse.ProfileInfo) {
+			*l = append(*l, fmt.Sprintf("profile info %d", p.Rows))
+		}),
+		clickhouse.WithProfileEvents(func(events []clickhouse.ProfileEvent) {
+			for _, e := range events {
+				*l = append(*l, fmt.Sprintf("profile event %s=%d", e.Name, e.Value))
+			}
+		}),
+	)
+}
+
+func (l *eventLog) add(event string) {
+	*l = append(*l, event)
+}
+
+func TestEventsDuringRowIteration(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	columns := []ColumnType{{Name: "id", Type: "UInt32"}}
+	mock.ExpectQuery("SELECT id FROM articles").
+		WillReturnRows(NewRows(columns, [][]any{{uint32(1)}, {uint32(2)}})).
+		WillEmitLogs(&clickhouse.Log{Text: "reading"}).
+		WillEmitProgress(&clickhouse.Progress{Rows: 1}, &clickhouse.Progress{Rows: 2}, &clickhouse.Progress{Rows: 3}).
+		WillEmitProfileInfo(&clickhouse.ProfileInfo{Rows: 2}).
+		WillEmitProfileEvents(clickhouse.ProfileEvent{Name: "SelectedRows", Value: 2})
+
+	var events eventLog
+	rows, err := mock.Query(events.context(), "SELECT id FROM articles")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Empty(t, events, "no events are emitted before the rows are iterated")
+	for rows.Next() {
+		var id uint32
+		assert.NoError(t, rows.Scan(&id))
+		events.add(fmt.Sprintf("row %d", id))
+	}
+	assert.NoError(t, rows.Close())
+
+	assert.Equal(t, eventLog{
+		"log reading",
+		"progress 1", "row 1",
+		"progress 2", "row 2",
+		"progress 3", "profile info 2", "profile event SelectedRows=2",
+	}, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventsWithoutRows(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectExec("OPTIMIZE TABLE articles").
+		WillEmitLogs(&clickhouse.Log{Text: "merging"}).
+		WillEmitProgress(&clickhouse.Progress{Rows: 10})
+	mock.ExpectPrepareBatch("INSERT INTO articles").
+		WillEmitProgress(&clickhouse.Progress{Rows: 1}).
+		WillEmitProfileEvents(clickhouse.ProfileEvent{Name: "InsertedRows", Value: 1})
+	mock.ExpectQueryRow("SELECT count() FROM articles").
+		WillReturnRow(NewRow([]ColumnType{{Name: "count()", Type: "UInt64"}}, []any{uint64(1)})).
+		WillEmitProgress(&clickhouse.Progress{Rows: 1}).
+		WillEmitProfileInfo(&clickhouse.ProfileInfo{Rows: 1})
+	mock.ExpectSelect("SELECT id FROM articles").
+		WillReturnRows(NewRows([]ColumnType{{Name: "id", Type: "UInt32"}}, [][]any{{uint32(1)}})).
+		WillEmitProgress(&clickhouse.Progress{Rows: 1})
+
+	var events eventLog
+	ctx := events.context()
+	assert.NoError(t, mock.Exec(ctx, "OPTIMIZE TABLE articles"))
+	assert.Equal(t, eventLog{"log merging", "progress 10"}, events)
+
+	events = nil
+	batch, err := mock.PrepareBatch(ctx, "INSERT INTO articles")
+	if assert.NoError(t, err) {
+		assert.Empty(t, events, "no events are emitted before the batch is sent")
+		assert.NoError(t, batch.Send())
+	}
+	assert.Equal(t, eventLog{"progress 1", "profile event InsertedRows=1"}, events)
+
+	events = nil
+	var count uint64
+	assert.NoError(t, mock.QueryRow(ctx, "SELECT count() FROM articles").Scan(&count))
+	assert.Equal(t, eventLog{"progress 1", "profile info 1"}, events)
+
+	events = nil
+	var ids []struct {
+		ID uint32 `ch:"id"`
+	}
+	assert.NoError(t, mock.Select(ctx, &ids, "SELECT id FROM articles"))
+	assert.Equal(t, eventLog{"progress 1"}, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventsWithoutCallbacks(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectExec("OPTIMIZE TABLE articles").WillEmitProgress(&clickhouse.Progress{Rows: 10})
+	var logs int
+	ctx := clickhouse.Context(context.Background(), clickhouse.WithLogs(func(*clickhouse.Log) {
+		logs++
+	}))
+	assert.NoError(t, mock.Exec(ctx, "OPTIMIZE TABLE articles"))
+	assert.Zero(t, logs)
+
+	mock.ExpectExec("OPTIMIZE TABLE articles").WillEmitProgress(&clickhouse.Progress{Rows: 10})
+	assert.NoError(t, mock.Exec(context.Background(), "OPTIMIZE TABLE articles"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	cmock "github.com/srikanthccv/ClickHouse-go-mock"
+)
+
+func main() {
+	mock, err := cmock.NewClickHouseNative(nil)
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.InOrder(
+		mock.ExpectExec("INSERT INTO sessions VALUES (?, ?)").
+			WithArgs(cmock.Regexp(`^user-\d+$`), cmock.TimeWithin(time.Now(), time.Minute)).
+			Times(2),
+		mock.ExpectExec("OPTIMIZE TABLE sessions FINAL").
+			WithMatcher(cmock.MatchEqual),
+	)
+
+	ctx := context.Background()
+	for _, user := range []string{"user-1", "user-2"} {
+		if err := mock.Exec(ctx, "INSERT INTO sessions VALUES (?, ?)", user, time.Now()); err != nil {
+			log.Fatalf("an error '%s' was not expected when inserting a session", err)
+		}
+	}
+	if err := mock.Exec(ctx, "OPTIMIZE TABLE sessions FINAL"); err != nil {
+		log.Fatalf("an error '%s' was not expected when optimizing a table", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		log.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
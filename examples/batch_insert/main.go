@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	cmock "github.com/srikanthccv/ClickHouse-go-mock"
+)
+
+type Article struct {
+	ID    uint32 `ch:"id"`
+	Title string `ch:"title"`
+}
+
+func main() {
+	mock, err := cmock.NewClickHouseNative(nil)
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	cols := []cmock.ColumnType{
+		{Name: "id", Type: "UInt32"},
+		{Name: "title", Type: "String"},
+	}
+
+	prep := mock.ExpectPrepareBatch("INSERT INTO articles").WithColumns(cols).ExpectRows(2)
+	prep.ExpectAppendStruct().Times(2)
+	prep.ExpectSend()
+
+	batch, err := mock.PrepareBatch(context.Background(), "INSERT INTO articles")
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when preparing a batch", err)
+	}
+	for _, article := range []*Article{{ID: 1, Title: "first"}, {ID: 2, Title: "second"}} {
+		if err := batch.AppendStruct(article); err != nil {
+			log.Fatalf("an error '%s' was not expected when appending a row", err)
+		}
+	}
+	if err := batch.Send(); err != nil {
+		log.Fatalf("an error '%s' was not expected when sending a batch", err)
+	}
+
+	if rows := prep.AppendedRows(); len(rows) != 2 || rows[1][1] != "second" {
+		log.Fatalf("unexpected rows appended: %v", rows)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		log.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -13,6 +13,7 @@
 package mockhouse
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strings"
@@ -26,21 +27,37 @@ import (
 // an expectation interface
 type expectation interface {
 	fulfilled() bool
+	exhausted() bool
 	Lock()
 	Unlock()
 	String() string
+	common() *commonExpectation
+
+	// method returns the name of the driver method the expectation is for
+	method() string
+
+	// match checks whether the call satisfies the expectation
+	match(queryMatcher QueryMatcher, cl *call) error
 }
 
 // common expectation struct
 // satisfies the expectation interface
 type commonExpectation struct {
 	sync.Mutex
-	triggered bool
-	err       error
+	repeat
+	err   error
+	delay time.Duration
+	group *group
+}
+
+func (e *commonExpectation) common() *commonExpectation {
+	return e
 }
 
-func (e *commonExpectation) fulfilled() bool {
-	return e.triggered
+// match is satisfied by any call, expectations which
+// need to inspect the call override it
+func (e *commonExpectation) match(QueryMatcher, *call) error {
+	return nil
 }
 
 // ExpectedClose is used to manage *driver.Conn.Close expectation
@@ -55,33 +72,58 @@ func (e *ExpectedClose) WillReturnError(err error) *ExpectedClose {
 	return e
 }
 
+func (e *ExpectedClose) method() string {
+	return "Close"
+}
+
 // String returns string representation
 func (e *ExpectedClose) String() string {
 	msg := "ExpectedClose => expecting database Close"
 	if e.err != nil {
 		msg += fmt.Sprintf(", which should return error: %s", e.err)
 	}
+	if calls := e.callsString(); calls != "" {
+		msg += ", " + calls
+	}
 	return msg
 }
 
+// Responder computes the result of a query from the call it is made with.
+// It is called each time the expectation it is set on is matched.
+type Responder func(ctx context.Context, query string, args []any) (*Rows, error)
+
+// BatchResponder computes the result of sending a batch from the rows
+// appended to it. It is called each time the batch is sent.
+type BatchResponder func(ctx context.Context, query string, rows [][]any) error
+
 // ExpectedQuery is used to manage *driver.Conn.Query expectations.
 // Returned by *clickhousemock.ExpectQuery.
 type ExpectedQuery struct {
 	queryBasedExpectation
 	rows             *Rows
-	delay            time.Duration
+	respond          Responder
 	rowsMustBeClosed bool
-	rowsWereClosed   bool
+	// rowsReturned and rowsClosed count the rows handed
+	// out by the calls of the expectation and closed since
+	rowsReturned int
+	rowsClosed   int
 }
 
 // WithArgs will match given expected args to actual database query arguments.
 // if at least one argument does not match, it will return an error. For specific
-// arguments an clickhousemock.Argument interface can be used to match an argument.
+// arguments an Argument interface, e.g. AnyArg or Regexp, can be used to match an argument.
 func (e *ExpectedQuery) WithArgs(args ...any) *ExpectedQuery {
 	e.args = args
 	return e
 }
 
+// WithAnyArgs accepts the *Conn.Query action whatever arguments it is called
+// with. Without WithArgs or WithAnyArgs it has to be called without arguments.
+func (e *ExpectedQuery) WithAnyArgs() *ExpectedQuery {
+	e.anyArgs = true
+	return e
+}
+
 // RowsWillBeClosed expects this query rows to be closed.
 func (e *ExpectedQuery) RowsWillBeClosed() *ExpectedQuery {
 	e.rowsMustBeClosed = true
@@ -107,14 +149,60 @@ func (e *ExpectedQuery) WillReturnRows(rows *Rows) *ExpectedQuery {
 	return e
 }
 
+// WillRespond allows to compute the rows, or an error, for expected
+// database query from the context, query and arguments it is called with.
+// It takes precedence over WillReturnRows.
+func (e *ExpectedQuery) WillRespond(respond Responder) *ExpectedQuery {
+	e.respond = respond
+	return e
+}
+
+// WithNamedArgs will match the driver.NamedValue arguments, e.g. created with
+// clickhouse.Named, passed to *Conn.Query by name. Values may be Arguments.
+func (e *ExpectedQuery) WithNamedArgs(args ...clikhouseDriver.NamedValue) *ExpectedQuery {
+	e.namedArgs = args
+	return e
+}
+
+// WithParameters will match the server-side {name:Type} parameters declared
+// in the *Conn.Query query against the values supplied with clickhouse.WithParameters
+// or, if none were, with named arguments.
+func (e *ExpectedQuery) WithParameters(params ...QueryParameter) *ExpectedQuery {
+	e.params = params
+	return e
+}
+
+// WithMatcher overrides the QueryMatcher of the mock for this expectation,
+// e.g. to match the *Conn.Query query with MatchRegexp while others are
+// matched exactly.
+func (e *ExpectedQuery) WithMatcher(matcher QueryMatcher) *ExpectedQuery {
+	e.matcher = matcher
+	return e
+}
+
+// MatchBound matches the expected SQL against the *Conn.Query query with its
+// arguments rendered into it the way clickhouse-go does before sending it,
+// e.g. quoting and escaping strings and formatting times and arrays.
+func (e *ExpectedQuery) MatchBound() *ExpectedQuery {
+	e.bound = true
+	return e
+}
+
+func (e *ExpectedQuery) method() string {
+	return "Query"
+}
+
 // String returns string representation
 func (e *ExpectedQuery) String() string {
-	msg := "ExpectedQuery => expecting Query, QueryContext or QueryRow which:"
+	msg := "ExpectedQuery => expecting Query which:"
 	msg += "\n  - matches sql: '" + e.expectSQL + "'"
 
-	if len(e.args) == 0 {
+	switch {
+	case e.anyArgs:
+		msg += "\n  - is with any arguments"
+	case len(e.args) == 0:
 		msg += "\n  - is without arguments"
-	} else {
+	default:
 		msg += "\n  - is with arguments:\n"
 		for i, arg := range e.args {
 			msg += fmt.Sprintf("    %d - %+v\n", i, arg)
@@ -130,6 +218,10 @@ func (e *ExpectedQuery) String() string {
 		msg += fmt.Sprintf("\n  - should return error: %s", e.err)
 	}
 
+	if calls := e.callsString(); calls != "" {
+		msg += "\n  - " + calls
+	}
+
 	return msg
 }
 
@@ -137,17 +229,23 @@ func (e *ExpectedQuery) String() string {
 // Returned by *clickhousemock.ExpectExec.
 type ExpectedExec struct {
 	queryBasedExpectation
-	delay time.Duration
 }
 
 // WithArgs will match given expected args to actual database exec operation arguments.
 // if at least one argument does not match, it will return an error. For specific
-// arguments an clickhousemock.Argument interface can be used to match an argument.
+// arguments an Argument interface, e.g. AnyArg or Regexp, can be used to match an argument.
 func (e *ExpectedExec) WithArgs(args ...any) *ExpectedExec {
 	e.args = args
 	return e
 }
 
+// WithAnyArgs accepts the *Conn.Exec action whatever arguments it is called
+// with. Without WithArgs or WithAnyArgs it has to be called without arguments.
+func (e *ExpectedExec) WithAnyArgs() *ExpectedExec {
+	e.anyArgs = true
+	return e
+}
+
 // WillReturnError allows to set an error for expected database exec action
 func (e *ExpectedExec) WillReturnError(err error) *ExpectedExec {
 	e.err = err
@@ -161,14 +259,52 @@ func (e *ExpectedExec) WillDelayFor(duration time.Duration) *ExpectedExec {
 	return e
 }
 
+// WithNamedArgs will match the driver.NamedValue arguments, e.g. created with
+// clickhouse.Named, passed to *Conn.Exec by name. Values may be Arguments.
+func (e *ExpectedExec) WithNamedArgs(args ...clikhouseDriver.NamedValue) *ExpectedExec {
+	e.namedArgs = args
+	return e
+}
+
+// WithParameters will match the server-side {name:Type} parameters declared
+// in the *Conn.Exec query against the values supplied with clickhouse.WithParameters
+// or, if none were, with named arguments.
+func (e *ExpectedExec) WithParameters(params ...QueryParameter) *ExpectedExec {
+	e.params = params
+	return e
+}
+
+// WithMatcher overrides the QueryMatcher of the mock for this expectation,
+// e.g. to match the *Conn.Exec query with MatchRegexp while others are
+// matched exactly.
+func (e *ExpectedExec) WithMatcher(matcher QueryMatcher) *ExpectedExec {
+	e.matcher = matcher
+	return e
+}
+
+// MatchBound matches the expected SQL against the *Conn.Exec query with its
+// arguments rendered into it the way clickhouse-go does before sending it,
+// e.g. quoting and escaping strings and formatting times and arrays.
+func (e *ExpectedExec) MatchBound() *ExpectedExec {
+	e.bound = true
+	return e
+}
+
+func (e *ExpectedExec) method() string {
+	return "Exec"
+}
+
 // String returns string representation
 func (e *ExpectedExec) String() string {
-	msg := "ExpectedExec => expecting Exec or ExecContext which:"
+	msg := "ExpectedExec => expecting Exec which:"
 	msg += "\n  - matches sql: '" + e.expectSQL + "'"
 
-	if len(e.args) == 0 {
+	switch {
+	case e.anyArgs:
+		msg += "\n  - is with any arguments"
+	case len(e.args) == 0:
 		msg += "\n  - is without arguments"
-	} else {
+	default:
 		msg += "\n  - is with arguments:\n"
 		var margs []string
 		for i, arg := range e.args {
@@ -181,6 +317,10 @@ func (e *ExpectedExec) String() string {
 		msg += fmt.Sprintf("\n  - should return error: %s", e.err)
 	}
 
+	if calls := e.callsString(); calls != "" {
+		msg += "\n  - " + calls
+	}
+
 	return msg
 }
 
@@ -190,18 +330,93 @@ type queryBasedExpectation struct {
 	commonExpectation
 	expectSQL string
 	args      []any
+	namedArgs []clikhouseDriver.NamedValue
+	params    []QueryParameter
+	matcher   QueryMatcher
+	// anyArgs is set to accept the call whatever its arguments are
+	anyArgs bool
+	// bound is set to match the query with the arguments rendered into it
+	bound bool
+	// ctxOptions are the clickhouse.Context options the call has to carry
+	ctxOptions contextExpectation
+	// events are emitted to the callbacks registered on the context of the call
+	events events
+}
+
+// actualSQL returns the SQL of the call the expectation is matched against.
+func (e *queryBasedExpectation) actualSQL(cl *call) string {
+	if e.bound {
+		return cl.bound
+	}
+	return cl.query
 }
 
+// queryMatcher returns the matcher set on the expectation
+// with WithMatcher, or the one of the mock otherwise.
+func (e *queryBasedExpectation) queryMatcher(mockMatcher QueryMatcher) QueryMatcher {
+	if e.matcher != nil {
+		return e.matcher
+	}
+	return mockMatcher
+}
+
+// match checks the query with the given matcher and, if any were
+// set, the call arguments, named arguments and query parameters
+func (e *queryBasedExpectation) match(queryMatcher QueryMatcher, cl *call) error {
+	if err := e.queryMatcher(queryMatcher).Match(e.expectSQL, e.actualSQL(cl)); err != nil {
+		return err
+	}
+	if err := e.matchArgs(cl.args); err != nil {
+		return fmt.Errorf("'%s' arguments do not match: %s", cl.query, err)
+	}
+	if err := e.matchNamedArgs(cl.args); err != nil {
+		return fmt.Errorf("'%s' arguments do not match: %s", cl.query, err)
+	}
+	if err := e.matchParameters(cl); err != nil {
+		return fmt.Errorf("'%s' parameters do not match: %s", cl.query, err)
+	}
+	if err := e.ctxOptions.match(cl); err != nil {
+		return fmt.Errorf("'%s' context options do not match: %s", cl.query, err)
+	}
+	return nil
+}
+
+// argsString returns the expected arguments for short string representations
+func (e *queryBasedExpectation) argsString() string {
+	if e.anyArgs {
+		return " with any arguments"
+	}
+	if len(e.args) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" with arguments %s", joinValues(e.args))
+}
+
+// matchArgs matches the positional arguments of a call. Without WithArgs
+// the call has to have none, unless WithAnyArgs was used or the arguments
+// are checked by name with WithNamedArgs or WithParameters, or as part
+// of the query with MatchBound.
 func (e *queryBasedExpectation) matchArgs(args []any) error {
-	if len(e.args) == 0 && len(args) == 0 {
+	switch {
+	case e.anyArgs, e.args == nil && e.bound:
+		return nil
+	case e.args == nil && (e.namedArgs != nil || e.params != nil):
+		return nil
+	}
+	return matchArgs(e.args, args)
+}
+
+// matchArgs matches args against the expected ones.
+func matchArgs(expected, args []any) error {
+	if len(expected) == 0 && len(args) == 0 {
 		return nil
 	}
 
-	if len(e.args) != len(args) {
-		return fmt.Errorf("expected %d arguments, got %d", len(e.args), len(args))
+	if len(expected) != len(args) {
+		return fmt.Errorf("expected %d arguments, got %d", len(expected), len(args))
 	}
 
-	for i, arg := range e.args {
+	for i, arg := range expected {
 		if err := matchArg(arg, args[i]); err != nil {
 			return fmt.Errorf("argument %d: %s", i, err)
 		}
@@ -215,6 +430,13 @@ func matchArg(expected, actual any) error {
 		return nil
 	}
 
+	if arg, ok := expected.(Argument); ok {
+		if arg.Match(actual) {
+			return nil
+		}
+		return fmt.Errorf("argument %v does not match %v (%T)", expected, actual, actual)
+	}
+
 	if reflect.DeepEqual(expected, actual) {
 		return nil
 	}
@@ -226,7 +448,7 @@ func matchArg(expected, actual any) error {
 // Returned by *clickhousemock.ExpectPing.
 type ExpectedPing struct {
 	commonExpectation
-	delay time.Duration
+	ctxOptions contextExpectation
 }
 
 // WillDelayFor allows to specify duration for which it will delay result. May
@@ -242,21 +464,27 @@ func (e *ExpectedPing) WillReturnError(err error) *ExpectedPing {
 	return e
 }
 
+func (e *ExpectedPing) method() string {
+	return "Ping"
+}
+
 // String returns string representation
 func (e *ExpectedPing) String() string {
 	msg := "ExpectedPing => expecting database Ping"
 	if e.err != nil {
 		msg += fmt.Sprintf(", which should return error: %s", e.err)
 	}
+	if calls := e.callsString(); calls != "" {
+		msg += ", " + calls
+	}
 	return msg
 }
 
 // ExpectedPrepareBatch is used to manage *driver.Conn.PrepareBatch expectations.
 // Returned by *clickhousemock.ExpectedPrepareBatch.
 type ExpectedPrepareBatch struct {
-	commonExpectation
+	queryBasedExpectation
 	expected        []expectation
-	expectSQL       string
 	abortErr        error
 	appendErr       error
 	appendStructErr error
@@ -264,10 +492,53 @@ type ExpectedPrepareBatch struct {
 	sendErr         error
 	closeErr        error
 	mustBeSent      bool
+	wasSent         bool
 	wasClosed       bool
-	delay           time.Duration
-	rows            int
-	isSent          bool
+	mustBeAborted   bool
+	wasAborted      bool
+	respond         BatchResponder
+	// rows is returned by Rows of the batch if set,
+	// instead of the number of rows appended
+	rows *int
+	// expectedRows is the number of rows the batch
+	// has to hold, if expectRows is set
+	expectedRows int
+	expectRows   bool
+	appendedRows int
+	// appended are the rows appended to the batches prepared for the expectation
+	appended [][]any
+	// columns and timezone describe the block appended values are converted with
+	columns  []ColumnType
+	timezone *time.Location
+}
+
+// WithColumns gives the batches prepared for this expectation the given
+// columns. Appended values are converted to them like clickhouse-go does,
+// returning the same errors for values of the wrong type. If the INSERT has
+// a column list, the columns named in it are taken, so the columns may be
+// the ones of the whole table. Options such as WithTimezone apply like
+// they do for NewRows.
+func (e *ExpectedPrepareBatch) WithColumns(columns []ColumnType, opts ...RowsOption) *ExpectedPrepareBatch {
+	options := defaultRowsOptions()
+	for _, opt := range opts {
+		opt(&options)
+	}
+	e.columns = columns
+	if e.columns == nil {
+		e.columns = []ColumnType{}
+	}
+	e.timezone = options.timezone
+	return e
+}
+
+// AppendedRows returns the rows appended to the batches prepared
+// for this expectation so far, in the order they were appended.
+func (e *ExpectedPrepareBatch) AppendedRows() [][]any {
+	e.Lock()
+	defer e.Unlock()
+	rows := make([][]any, len(e.appended))
+	copy(rows, e.appended)
+	return rows
 }
 
 // WillReturnError allows to set an error for the expected *driver.Conn.PrepareBatch action.
@@ -283,17 +554,48 @@ func (e *ExpectedPrepareBatch) WillDelayFor(duration time.Duration) *ExpectedPre
 	return e
 }
 
-// WillBeSent expects this prepared batch statement to
-// be set with sent.
+// WillRespond allows to compute the result of Send on this prepared batch
+// statement from the rows appended to it. An error set with ExpectSend
+// takes precedence.
+func (e *ExpectedPrepareBatch) WillRespond(respond BatchResponder) *ExpectedPrepareBatch {
+	e.respond = respond
+	return e
+}
+
+// WithMatcher overrides the QueryMatcher of the mock for this expectation,
+// e.g. to match the *Conn.PrepareBatch query with MatchRegexp while others
+// are matched exactly.
+func (e *ExpectedPrepareBatch) WithMatcher(matcher QueryMatcher) *ExpectedPrepareBatch {
+	e.matcher = matcher
+	return e
+}
+
+// WillBeSent expects this prepared batch statement to be
+// sent, which ExpectationsWereMet checks.
 func (e *ExpectedPrepareBatch) WillBeSent() *ExpectedPrepareBatch {
 	e.mustBeSent = true
 	return e
 }
 
-// WillReturnRows expects this prepared batch statement to
-// be set with rows.
+// WillBeAborted expects this prepared batch statement to
+// be aborted, which ExpectationsWereMet checks.
+func (e *ExpectedPrepareBatch) WillBeAborted() *ExpectedPrepareBatch {
+	e.mustBeAborted = true
+	return e
+}
+
+// WillReturnRows allows to set the number of rows returned by Rows of
+// this prepared batch statement, instead of the number of rows appended.
 func (e *ExpectedPrepareBatch) WillReturnRows(rows int) *ExpectedPrepareBatch {
-	e.rows = rows
+	e.rows = &rows
+	return e
+}
+
+// ExpectRows expects the given number of rows to be appended to this
+// prepared batch statement, which ExpectationsWereMet checks.
+func (e *ExpectedPrepareBatch) ExpectRows(rows int) *ExpectedPrepareBatch {
+	e.expectedRows = rows
+	e.expectRows = true
 	return e
 }
 
@@ -310,8 +612,26 @@ func (e *ExpectedAbort) WillReturnError(err error) *ExpectedAbort {
 	return e
 }
 
+func (e *ExpectedAbort) method() string {
+	return "Abort"
+}
+
 func (e *ExpectedAbort) String() string {
-	return fmt.Sprintf("Abort(%s)", e.expectSQL)
+	return fmt.Sprintf("Abort(%s)", e.expectSQL) + e.callsSuffix()
+}
+
+// expect queues a step of the batch after the ones already set.
+func (e *ExpectedPrepareBatch) expect(step expectation) {
+	e.Lock()
+	e.expected = append(e.expected, step)
+	e.Unlock()
+}
+
+// steps returns the steps of the batch set so far.
+func (e *ExpectedPrepareBatch) steps() []expectation {
+	e.Lock()
+	defer e.Unlock()
+	return e.expected
 }
 
 // ExpectAbort allows to expect Abort() on this prepared batch statement.
@@ -319,7 +639,7 @@ func (e *ExpectedPrepareBatch) ExpectAbort() *ExpectedAbort {
 	eq := &ExpectedAbort{}
 	eq.expectSQL = e.expectSQL
 	eq.expBatch = e
-	e.expected = append(e.expected, eq)
+	e.expect(eq)
 	return eq
 }
 
@@ -327,6 +647,25 @@ type ExpectedAppend struct {
 	commonExpectation
 	expBatch  *ExpectedPrepareBatch
 	expectSQL string
+	args      []any
+}
+
+// WithArgs will match given expected values to the row appended with *batch.Append.
+// if at least one value does not match, Append will return an error. For specific
+// values an Argument interface, e.g. AnyArg or Regexp, can be used to match a value.
+func (e *ExpectedAppend) WithArgs(args ...any) *ExpectedAppend {
+	e.args = args
+	return e
+}
+
+func (e *ExpectedAppend) match(_ QueryMatcher, cl *call) error {
+	if e.args == nil {
+		return nil
+	}
+	if err := matchArgs(e.args, cl.args); err != nil {
+		return fmt.Errorf("appended row does not match: %s", err)
+	}
+	return nil
 }
 
 // WillReturnError allows to set an error for the expected *batch.Append action.
@@ -336,8 +675,16 @@ func (e *ExpectedAppend) WillReturnError(err error) *ExpectedAppend {
 	return e
 }
 
+func (e *ExpectedAppend) method() string {
+	return "Append"
+}
+
 func (e *ExpectedAppend) String() string {
-	return fmt.Sprintf("Append(%s)", e.expectSQL)
+	msg := fmt.Sprintf("Append(%s)", e.expectSQL)
+	if len(e.args) != 0 {
+		msg += fmt.Sprintf(" with arguments %s", joinValues(e.args))
+	}
+	return msg + e.callsSuffix()
 }
 
 // ExpectAppend allows to expect Append() on this prepared batch statement.
@@ -346,7 +693,7 @@ func (e *ExpectedPrepareBatch) ExpectAppend() *ExpectedAppend {
 	eq := &ExpectedAppend{}
 	eq.expectSQL = e.expectSQL
 	eq.expBatch = e
-	e.expected = append(e.expected, eq)
+	e.expect(eq)
 	return eq
 }
 
@@ -363,8 +710,12 @@ func (e *ExpectedAppendStruct) WillReturnError(err error) *ExpectedAppendStruct
 	return e
 }
 
+func (e *ExpectedAppendStruct) method() string {
+	return "AppendStruct"
+}
+
 func (e *ExpectedAppendStruct) String() string {
-	return fmt.Sprintf("AppendStruct(%s)", e.expectSQL)
+	return fmt.Sprintf("AppendStruct(%s)", e.expectSQL) + e.callsSuffix()
 }
 
 // ExpectAppendStruct allows to expect AppendStruct() on this prepared batch statement.
@@ -372,7 +723,7 @@ func (e *ExpectedPrepareBatch) ExpectAppendStruct() *ExpectedAppendStruct {
 	eq := &ExpectedAppendStruct{}
 	eq.expectSQL = e.expectSQL
 	eq.expBatch = e
-	e.expected = append(e.expected, eq)
+	e.expect(eq)
 	return eq
 }
 
@@ -381,24 +732,63 @@ type ExpectedColumn struct {
 	expectSQL   string
 	expBatch    *ExpectedPrepareBatch
 	batchCoulmn clikhouseDriver.BatchColumn
+	index       int
+	values      []any
 }
 
-// WillReturnBatchColumn allows to set an error for the expected *batch.Column action.
+// WillReturnBatchColumn allows to set the column returned by the expected *batch.Column action
+// instead of the one recording the appended values.
 func (e *ExpectedColumn) WillReturnBatchColumn(batchcolumn clikhouseDriver.BatchColumn) *ExpectedColumn {
 	e.batchCoulmn = batchcolumn
 	return e
 }
 
+// WithValues expects the column to hold exactly the given values when the
+// batch is sent, otherwise Send returns an error. For specific values an
+// Argument interface, e.g. AnyArg or Regexp, can be used to match a value.
+func (e *ExpectedColumn) WithValues(values ...any) *ExpectedColumn {
+	e.values = values
+	if e.values == nil {
+		e.values = []any{}
+	}
+	return e
+}
+
+func (e *ExpectedColumn) match(_ QueryMatcher, cl *call) error {
+	if i, _ := cl.args[0].(int); i != e.index {
+		return fmt.Errorf("expected column %d, got %d", e.index, i)
+	}
+	return nil
+}
+
+// matchValues matches the values appended to the column.
+func (e *ExpectedColumn) matchValues(values []any) error {
+	if len(e.values) != len(values) {
+		return fmt.Errorf("expected %d values, got %d", len(e.values), len(values))
+	}
+	for i, value := range e.values {
+		if err := matchArg(value, values[i]); err != nil {
+			return fmt.Errorf("value %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+func (e *ExpectedColumn) method() string {
+	return "Column"
+}
+
 func (e *ExpectedColumn) String() string {
-	return fmt.Sprintf("Column(%s)", e.expectSQL)
+	return fmt.Sprintf("Column(%s, %d)", e.expectSQL, e.index) + e.callsSuffix()
 }
 
-// ExpectColumn allows to expect Column() on this prepared batch statement.
-func (e *ExpectedPrepareBatch) ExpectColumn() *ExpectedColumn {
+// ExpectColumn allows to expect Column(i) on this prepared batch statement.
+func (e *ExpectedPrepareBatch) ExpectColumn(i int) *ExpectedColumn {
 	eq := &ExpectedColumn{}
 	eq.expectSQL = e.expectSQL
 	eq.expBatch = e
-	e.expected = append(e.expected, eq)
+	eq.index = i
+	e.expect(eq)
 	return eq
 }
 
@@ -415,8 +805,12 @@ func (e *ExpectedFlush) WillReturnError(err error) *ExpectedFlush {
 	return e
 }
 
+func (e *ExpectedFlush) method() string {
+	return "Flush"
+}
+
 func (e *ExpectedFlush) String() string {
-	return fmt.Sprintf("Flush(%s)", e.expectSQL)
+	return fmt.Sprintf("Flush(%s)", e.expectSQL) + e.callsSuffix()
 }
 
 // ExpectFlush allows to expect Flush() on this prepared batch statement.
@@ -424,7 +818,7 @@ func (e *ExpectedPrepareBatch) ExpectFlush() *ExpectedFlush {
 	eq := &ExpectedFlush{}
 	eq.expectSQL = e.expectSQL
 	eq.expBatch = e
-	e.expected = append(e.expected, eq)
+	e.expect(eq)
 	return eq
 }
 
@@ -441,8 +835,12 @@ func (e *ExpectedSend) WillReturnError(err error) *ExpectedSend {
 	return e
 }
 
+func (e *ExpectedSend) method() string {
+	return "Send"
+}
+
 func (e *ExpectedSend) String() string {
-	return fmt.Sprintf("Send(%s)", e.expectSQL)
+	return fmt.Sprintf("Send(%s)", e.expectSQL) + e.callsSuffix()
 }
 
 // ExpectSend allows to expect Send() on this prepared batch statement.
@@ -450,7 +848,7 @@ func (e *ExpectedPrepareBatch) ExpectSend() *ExpectedSend {
 	eq := &ExpectedSend{}
 	eq.expectSQL = e.expectSQL
 	eq.expBatch = e
-	e.expected = append(e.expected, eq)
+	e.expect(eq)
 	return eq
 }
 
@@ -458,19 +856,24 @@ type ExpectedIsSent struct {
 	commonExpectation
 	expectSQL string
 	expBatch  *ExpectedPrepareBatch
-	isSent    bool
+	isSent    *bool
 }
 
-// WillReturnBool allows to set an bool for the expected *batch.IsSent action.
+// WillReturnBool allows to set the bool returned by the expected *batch.IsSent
+// action instead of whether the batch was sent, and expects the batch to be
+// sent if it is true.
 func (e *ExpectedIsSent) WillReturnBool(b bool) *ExpectedIsSent {
-	e.isSent = b
-	e.expBatch.isSent = b
+	e.isSent = &b
 	e.expBatch.mustBeSent = b
 	return e
 }
 
+func (e *ExpectedIsSent) method() string {
+	return "IsSent"
+}
+
 func (e *ExpectedIsSent) String() string {
-	return fmt.Sprintf("IsSent(%s)", e.expectSQL)
+	return fmt.Sprintf("IsSent(%s)", e.expectSQL) + e.callsSuffix()
 }
 
 // ExpectIsSent allows to expect IsSent() on this prepared batch statement.
@@ -478,7 +881,7 @@ func (e *ExpectedPrepareBatch) ExpectIsSent() *ExpectedIsSent {
 	eq := &ExpectedIsSent{}
 	eq.expectSQL = e.expectSQL
 	eq.expBatch = e
-	e.expected = append(e.expected, eq)
+	e.expect(eq)
 	return eq
 }
 
@@ -495,8 +898,12 @@ func (e *ExpectedBatchClose) WillReturnError(err error) *ExpectedBatchClose {
 	return e
 }
 
+func (e *ExpectedBatchClose) method() string {
+	return "Close"
+}
+
 func (e *ExpectedBatchClose) String() string {
-	return fmt.Sprintf("Close(%s)", e.expectSQL)
+	return fmt.Sprintf("Close(%s)", e.expectSQL) + e.callsSuffix()
 }
 
 // ExpectClose allows to expect Close() on this prepared batch statement.
@@ -504,10 +911,14 @@ func (e *ExpectedPrepareBatch) ExpectClose() *ExpectedBatchClose {
 	eq := &ExpectedBatchClose{}
 	eq.expectSQL = e.expectSQL
 	eq.expBatch = e
-	e.expected = append(e.expected, eq)
+	e.expect(eq)
 	return eq
 }
 
+func (e *ExpectedPrepareBatch) method() string {
+	return "PrepareBatch"
+}
+
 // String returns string representation
 func (e *ExpectedPrepareBatch) String() string {
 	msg := "ExpectedPrepareBatch => expecting PrepareBatch statement which:"
@@ -545,6 +956,18 @@ func (e *ExpectedPrepareBatch) String() string {
 		msg += "\n  - should be sent"
 	}
 
+	if e.mustBeAborted {
+		msg += "\n  - should be aborted"
+	}
+
+	if e.expectRows {
+		msg += fmt.Sprintf("\n  - should have %d rows", e.expectedRows)
+	}
+
+	if calls := e.callsString(); calls != "" {
+		msg += "\n  - " + calls
+	}
+
 	return msg
 }
 
@@ -560,16 +983,19 @@ func (e *ExpectedStats) WillReturnStats(stats clikhouseDriver.Stats) *ExpectedSt
 	return e
 }
 
+func (e *ExpectedStats) method() string {
+	return "Stats"
+}
+
 func (e *ExpectedStats) String() string {
-	return fmt.Sprintf("Stats(%s)", e.expectSQL)
+	return fmt.Sprintf("Stats(%s)", e.expectSQL) + e.callsSuffix()
 }
 
 type ExpectedAsyncInsert struct {
-	commonExpectation
-	expectSQL  string
+	queryBasedExpectation
 	expectWait bool
-	wait       bool
-	delay      time.Duration
+	// values are the rows the VALUES clause has to hold, if set
+	values [][]any
 }
 
 // WillReturnError allows to set an error for the expected *Conn.AsyncInsert action.
@@ -578,9 +1004,10 @@ func (e *ExpectedAsyncInsert) WillReturnError(err error) *ExpectedAsyncInsert {
 	return e
 }
 
-// WillWait allows to set a wait for the expected *Conn.AsyncInsert action.
+// WillWait expects the *Conn.AsyncInsert action to wait for the
+// insert to be processed, as if ExpectAsyncInsert was given true.
 func (e *ExpectedAsyncInsert) WillWait() *ExpectedAsyncInsert {
-	e.wait = true
+	e.expectWait = true
 	return e
 }
 
@@ -590,15 +1017,64 @@ func (e *ExpectedAsyncInsert) WillDelay(d time.Duration) *ExpectedAsyncInsert {
 	return e
 }
 
+// WithArgs will match given expected args to actual *Conn.AsyncInsert arguments.
+// if at least one argument does not match, it will return an error. For specific
+// arguments an Argument interface, e.g. AnyArg or Regexp, can be used to match an argument.
+func (e *ExpectedAsyncInsert) WithArgs(args ...any) *ExpectedAsyncInsert {
+	e.args = args
+	return e
+}
+
+// WithAnyArgs accepts the *Conn.AsyncInsert action whatever arguments it is called
+// with. Without WithArgs or WithAnyArgs it has to be called without arguments.
+func (e *ExpectedAsyncInsert) WithAnyArgs() *ExpectedAsyncInsert {
+	e.anyArgs = true
+	return e
+}
+
+// WithNamedArgs will match the driver.NamedValue arguments, e.g. created with
+// clickhouse.Named, passed to *Conn.AsyncInsert by name. Values may be Arguments.
+func (e *ExpectedAsyncInsert) WithNamedArgs(args ...clikhouseDriver.NamedValue) *ExpectedAsyncInsert {
+	e.namedArgs = args
+	return e
+}
+
+// WithParameters will match the server-side {name:Type} parameters declared
+// in the *Conn.AsyncInsert query against the values supplied with clickhouse.WithParameters
+// or, if none were, with named arguments.
+func (e *ExpectedAsyncInsert) WithParameters(params ...QueryParameter) *ExpectedAsyncInsert {
+	e.params = params
+	return e
+}
+
+// WithMatcher overrides the QueryMatcher of the mock for this expectation,
+// e.g. to match the *Conn.AsyncInsert query with MatchRegexp while others are
+// matched exactly.
+func (e *ExpectedAsyncInsert) WithMatcher(matcher QueryMatcher) *ExpectedAsyncInsert {
+	e.matcher = matcher
+	return e
+}
+
+// MatchBound matches the expected SQL against the *Conn.AsyncInsert query with its
+// arguments rendered into it the way clickhouse-go does before sending it,
+// e.g. quoting and escaping strings and formatting times and arrays.
+func (e *ExpectedAsyncInsert) MatchBound() *ExpectedAsyncInsert {
+	e.bound = true
+	return e
+}
+
+func (e *ExpectedAsyncInsert) method() string {
+	return "AsyncInsert"
+}
+
 func (e *ExpectedAsyncInsert) String() string {
-	return fmt.Sprintf("AsyncInsert(%s)", e.expectSQL)
+	return fmt.Sprintf("AsyncInsert(%s)%s", e.expectSQL, e.argsString()) + e.callsSuffix()
 }
 
 type ExpectedQueryRow struct {
-	commonExpectation
-	expectSQL string
-	row       *Row
-	delay     time.Duration
+	queryBasedExpectation
+	row     *Row
+	respond Responder
 }
 
 // WillReturnRow allows to set a row for the expected *Conn.QueryRow action.
@@ -607,8 +1083,17 @@ func (e *ExpectedQueryRow) WillReturnRow(row *Row) *ExpectedQueryRow {
 	return e
 }
 
+// WillRespond allows to compute the row for the expected *Conn.QueryRow action
+// from the context, query and arguments it is called with. The first of the
+// returned rows is scanned. It takes precedence over WillReturnRow.
+func (e *ExpectedQueryRow) WillRespond(respond Responder) *ExpectedQueryRow {
+	e.respond = respond
+	return e
+}
+
+// WillReturnError allows to set an error for the expected *Conn.QueryRow action.
+// The error is reported by the returned row.
 func (e *ExpectedQueryRow) WillReturnError(err error) *ExpectedQueryRow {
-	e.row.err = err
 	e.err = err
 	return e
 }
@@ -619,22 +1104,80 @@ func (e *ExpectedQueryRow) WillDelay(d time.Duration) *ExpectedQueryRow {
 	return e
 }
 
+// WithArgs will match given expected args to actual *Conn.QueryRow arguments.
+// if at least one argument does not match, it will return an error. For specific
+// arguments an Argument interface, e.g. AnyArg or Regexp, can be used to match an argument.
+func (e *ExpectedQueryRow) WithArgs(args ...any) *ExpectedQueryRow {
+	e.args = args
+	return e
+}
+
+// WithAnyArgs accepts the *Conn.QueryRow action whatever arguments it is called
+// with. Without WithArgs or WithAnyArgs it has to be called without arguments.
+func (e *ExpectedQueryRow) WithAnyArgs() *ExpectedQueryRow {
+	e.anyArgs = true
+	return e
+}
+
+// WithNamedArgs will match the driver.NamedValue arguments, e.g. created with
+// clickhouse.Named, passed to *Conn.QueryRow by name. Values may be Arguments.
+func (e *ExpectedQueryRow) WithNamedArgs(args ...clikhouseDriver.NamedValue) *ExpectedQueryRow {
+	e.namedArgs = args
+	return e
+}
+
+// WithParameters will match the server-side {name:Type} parameters declared
+// in the *Conn.QueryRow query against the values supplied with clickhouse.WithParameters
+// or, if none were, with named arguments.
+func (e *ExpectedQueryRow) WithParameters(params ...QueryParameter) *ExpectedQueryRow {
+	e.params = params
+	return e
+}
+
+// WithMatcher overrides the QueryMatcher of the mock for this expectation,
+// e.g. to match the *Conn.QueryRow query with MatchRegexp while others are
+// matched exactly.
+func (e *ExpectedQueryRow) WithMatcher(matcher QueryMatcher) *ExpectedQueryRow {
+	e.matcher = matcher
+	return e
+}
+
+// MatchBound matches the expected SQL against the *Conn.QueryRow query with its
+// arguments rendered into it the way clickhouse-go does before sending it,
+// e.g. quoting and escaping strings and formatting times and arrays.
+func (e *ExpectedQueryRow) MatchBound() *ExpectedQueryRow {
+	e.bound = true
+	return e
+}
+
+func (e *ExpectedQueryRow) method() string {
+	return "QueryRow"
+}
+
 func (e *ExpectedQueryRow) String() string {
-	return fmt.Sprintf("QueryRow(%s)", e.expectSQL)
+	return fmt.Sprintf("QueryRow(%s)%s", e.expectSQL, e.argsString()) + e.callsSuffix()
 }
 
 type ExpectedSelect struct {
-	commonExpectation
-	expectSQL string
-	delay     time.Duration
-	rows      *Rows
+	queryBasedExpectation
+	rows    *Rows
+	respond Responder
 }
 
+// WillReturnRows allows to set rows scanned into the destination of the expected *Conn.Select action.
 func (e *ExpectedSelect) WillReturnRows(rows *Rows) *ExpectedSelect {
 	e.rows = rows
 	return e
 }
 
+// WillRespond allows to compute the rows for the expected *Conn.Select action
+// from the context, query and arguments it is called with.
+// It takes precedence over WillReturnRows.
+func (e *ExpectedSelect) WillRespond(respond Responder) *ExpectedSelect {
+	e.respond = respond
+	return e
+}
+
 // WillReturnError allows to set an error for the expected *Conn.Select action.
 func (e *ExpectedSelect) WillReturnError(err error) *ExpectedSelect {
 	e.err = err
@@ -647,8 +1190,58 @@ func (e *ExpectedSelect) WillDelay(d time.Duration) *ExpectedSelect {
 	return e
 }
 
+// WithArgs will match given expected args to actual *Conn.Select arguments.
+// if at least one argument does not match, it will return an error. For specific
+// arguments an Argument interface, e.g. AnyArg or Regexp, can be used to match an argument.
+func (e *ExpectedSelect) WithArgs(args ...any) *ExpectedSelect {
+	e.args = args
+	return e
+}
+
+// WithAnyArgs accepts the *Conn.Select action whatever arguments it is called
+// with. Without WithArgs or WithAnyArgs it has to be called without arguments.
+func (e *ExpectedSelect) WithAnyArgs() *ExpectedSelect {
+	e.anyArgs = true
+	return e
+}
+
+// WithNamedArgs will match the driver.NamedValue arguments, e.g. created with
+// clickhouse.Named, passed to *Conn.Select by name. Values may be Arguments.
+func (e *ExpectedSelect) WithNamedArgs(args ...clikhouseDriver.NamedValue) *ExpectedSelect {
+	e.namedArgs = args
+	return e
+}
+
+// WithParameters will match the server-side {name:Type} parameters declared
+// in the *Conn.Select query against the values supplied with clickhouse.WithParameters
+// or, if none were, with named arguments.
+func (e *ExpectedSelect) WithParameters(params ...QueryParameter) *ExpectedSelect {
+	e.params = params
+	return e
+}
+
+// WithMatcher overrides the QueryMatcher of the mock for this expectation,
+// e.g. to match the *Conn.Select query with MatchRegexp while others are
+// matched exactly.
+func (e *ExpectedSelect) WithMatcher(matcher QueryMatcher) *ExpectedSelect {
+	e.matcher = matcher
+	return e
+}
+
+// MatchBound matches the expected SQL against the *Conn.Select query with its
+// arguments rendered into it the way clickhouse-go does before sending it,
+// e.g. quoting and escaping strings and formatting times and arrays.
+func (e *ExpectedSelect) MatchBound() *ExpectedSelect {
+	e.bound = true
+	return e
+}
+
+func (e *ExpectedSelect) method() string {
+	return "Select"
+}
+
 func (e *ExpectedSelect) String() string {
-	return fmt.Sprintf("Select(%s)", e.expectSQL)
+	return fmt.Sprintf("Select(%s)%s", e.expectSQL, e.argsString()) + e.callsSuffix()
 }
 
 type ExpectedServerVersion struct {
@@ -662,8 +1255,12 @@ func (e *ExpectedServerVersion) WillReturnVersion(version proto.ServerHandshake)
 	return e
 }
 
+func (e *ExpectedServerVersion) method() string {
+	return "ServerVersion"
+}
+
 func (e *ExpectedServerVersion) String() string {
-	return "ServerVersion() - Mocked"
+	return "ServerVersion() - Mocked" + e.callsSuffix()
 }
 
 type ExpectedContributors struct {
@@ -677,6 +1274,10 @@ func (e *ExpectedContributors) WillReturnContributors(contributors ...string) *E
 	return e
 }
 
+func (e *ExpectedContributors) method() string {
+	return "Contributors"
+}
+
 func (e *ExpectedContributors) String() string {
-	return "Contributors() - Mocked"
+	return "Contributors() - Mocked" + e.callsSuffix()
 }
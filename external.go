@@ -0,0 +1,157 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ClickHouse/clickhouse-go/v2/ext"
+)
+
+// ExternalTable is a table shipped alongside a query with
+// clickhouse.WithExternalTable, as the mock received it.
+type ExternalTable struct {
+	Name    string
+	Columns []ColumnType
+	Rows    [][]any
+}
+
+// externalTables reads the name, columns and rows of the given tables.
+func externalTables(tables []*ext.Table) []ExternalTable {
+	if len(tables) == 0 {
+		return nil
+	}
+	result := make([]ExternalTable, 0, len(tables))
+	for _, table := range tables {
+		if table == nil {
+			continue
+		}
+		block := table.Block()
+		t := ExternalTable{
+			Name:    table.Name(),
+			Columns: make([]ColumnType, 0, len(block.Columns)),
+			Rows:    make([][]any, 0, block.Rows()),
+		}
+		for _, col := range block.Columns {
+			t.Columns = append(t.Columns, ColumnType{Name: col.Name(), Type: col.Type()})
+		}
+		for i := 0; i < block.Rows(); i++ {
+			row := make([]any, 0, len(block.Columns))
+			for _, col := range block.Columns {
+				row = append(row, col.Row(i, false))
+			}
+			t.Rows = append(t.Rows, row)
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+// externalTableExpectation is an external table a call has to ship.
+// Columns and rows left nil are not checked.
+type externalTableExpectation struct {
+	name    string
+	columns []ColumnType
+	rows    [][]any
+}
+
+// match checks the table named like the expectation among the given ones.
+func (e *externalTableExpectation) match(tables []ExternalTable) error {
+	var table *ExternalTable
+	for i := range tables {
+		if tables[i].Name == e.name {
+			table = &tables[i]
+			break
+		}
+	}
+	if table == nil {
+		return fmt.Errorf("external table %q was not shipped", e.name)
+	}
+
+	if e.columns != nil && !reflect.DeepEqual(e.columns, table.Columns) {
+		return fmt.Errorf("external table %q: expected columns %v, got %v", e.name, e.columns, table.Columns)
+	}
+	if e.rows == nil {
+		return nil
+	}
+	if len(e.rows) != len(table.Rows) {
+		return fmt.Errorf("external table %q: expected %d rows, got %d", e.name, len(e.rows), len(table.Rows))
+	}
+	for i, row := range e.rows {
+		if len(row) != len(table.Rows[i]) {
+			return fmt.Errorf("external table %q: row %d: expected %d values, got %d",
+				e.name, i, len(row), len(table.Rows[i]))
+		}
+		for j, value := range row {
+			if err := matchArg(value, table.Rows[i][j]); err != nil {
+				return fmt.Errorf("external table %q: row %d: column %d: %s", e.name, i, j, err)
+			}
+		}
+	}
+	return nil
+}
+
+// WithExternalTable expects the Query call to ship an external table with the
+// given name, set with clickhouse.WithExternalTable. If columns are not nil
+// the table must have exactly those columns, and if rows are not nil exactly
+// those rows. Values may be Arguments.
+func (e *ExpectedQuery) WithExternalTable(name string, columns []ColumnType, rows [][]any) *ExpectedQuery {
+	e.ctxOptions.external = append(e.ctxOptions.external, externalTableExpectation{name: name, columns: columns, rows: rows})
+	return e
+}
+
+// WithExternalTable expects the QueryRow call to ship an external table with the
+// given name, set with clickhouse.WithExternalTable. If columns are not nil
+// the table must have exactly those columns, and if rows are not nil exactly
+// those rows. Values may be Arguments.
+func (e *ExpectedQueryRow) WithExternalTable(name string, columns []ColumnType, rows [][]any) *ExpectedQueryRow {
+	e.ctxOptions.external = append(e.ctxOptions.external, externalTableExpectation{name: name, columns: columns, rows: rows})
+	return e
+}
+
+// WithExternalTable expects the Select call to ship an external table with the
+// given name, set with clickhouse.WithExternalTable. If columns are not nil
+// the table must have exactly those columns, and if rows are not nil exactly
+// those rows. Values may be Arguments.
+func (e *ExpectedSelect) WithExternalTable(name string, columns []ColumnType, rows [][]any) *ExpectedSelect {
+	e.ctxOptions.external = append(e.ctxOptions.external, externalTableExpectation{name: name, columns: columns, rows: rows})
+	return e
+}
+
+// WithExternalTable expects the Exec call to ship an external table with the
+// given name, set with clickhouse.WithExternalTable. If columns are not nil
+// the table must have exactly those columns, and if rows are not nil exactly
+// those rows. Values may be Arguments.
+func (e *ExpectedExec) WithExternalTable(name string, columns []ColumnType, rows [][]any) *ExpectedExec {
+	e.ctxOptions.external = append(e.ctxOptions.external, externalTableExpectation{name: name, columns: columns, rows: rows})
+	return e
+}
+
+// WithExternalTable expects the AsyncInsert call to ship an external table
+// with the given name, set with clickhouse.WithExternalTable. If columns are
+// not nil the table must have exactly those columns, and if rows are not nil
+// exactly those rows. Values may be Arguments.
+func (e *ExpectedAsyncInsert) WithExternalTable(name string, columns []ColumnType, rows [][]any) *ExpectedAsyncInsert {
+	e.ctxOptions.external = append(e.ctxOptions.external, externalTableExpectation{name: name, columns: columns, rows: rows})
+	return e
+}
+
+// WithExternalTable expects the PrepareBatch call to ship an external table
+// with the given name, set with clickhouse.WithExternalTable. If columns are
+// not nil the table must have exactly those columns, and if rows are not nil
+// exactly those rows. Values may be Arguments.
+func (e *ExpectedPrepareBatch) WithExternalTable(name string, columns []ColumnType, rows [][]any) *ExpectedPrepareBatch {
+	e.ctxOptions.external = append(e.ctxOptions.external, externalTableExpectation{name: name, columns: columns, rows: rows})
+	return e
+}
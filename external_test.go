@@ -0,0 +1,130 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/ext"
+	"github.com/stretchr/testify/assert"
+)
+
+func newIDsTable(t *testing.T, ids ...uint32) *ext.Table {
+	t.Helper()
+	table, err := ext.NewTable("ids", ext.Column("id", "UInt32"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range ids {
+		if err := table.Append(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return table
+}
+
+func TestExternalTables(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	columns := []ColumnType{{Name: "id", Type: "UInt32"}}
+	mock.ExpectQuery("SELECT title FROM articles WHERE id IN ids").
+		WithExternalTable("ids", columns, [][]any{{uint32(1)}, {AnyArg()}})
+	mock.ExpectExec("DELETE FROM articles WHERE id IN ids").
+		WithExternalTable("ids", nil, nil)
+	mock.ExpectAsyncInsert("INSERT INTO archive SELECT * FROM articles WHERE id IN ids", false).
+		WithExternalTable("ids", columns, nil)
+	mock.ExpectPrepareBatch("INSERT INTO archive").
+		WithExternalTable("ids", nil, [][]any{{uint32(1)}, {uint32(2)}})
+
+	ctx := clickhouse.Context(context.Background(), clickhouse.WithExternalTable(newIDsTable(t, 1, 2)))
+	_, err = mock.Query(ctx, "SELECT title FROM articles WHERE id IN ids")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.Exec(ctx, "DELETE FROM articles WHERE id IN ids"))
+	assert.NoError(t, mock.AsyncInsert(ctx, "INSERT INTO archive SELECT * FROM articles WHERE id IN ids", false))
+	_, err = mock.PrepareBatch(ctx, "INSERT INTO archive")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectPrepareBatch("INSERT INTO archive").WithExternalTable("ids", nil, nil)
+	_, err = mock.PrepareBatch(context.Background(), "INSERT INTO archive")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `external table "ids" was not shipped`)
+	}
+
+	calls := mock.Calls()
+	if assert.Len(t, calls, 5) {
+		assert.Equal(t, []ExternalTable{{
+			Name:    "ids",
+			Columns: columns,
+			Rows:    [][]any{{uint32(1)}, {uint32(2)}},
+		}}, calls[0].ExternalTables)
+	}
+}
+
+func TestExternalTableMismatches(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name    string
+		columns []ColumnType
+		rows    [][]any
+		tables  []*ext.Table
+		err     string
+	}{
+		{
+			name: "missing table",
+			err:  `external table "ids" was not shipped`,
+		},
+		{
+			name:    "columns",
+			columns: []ColumnType{{Name: "id", Type: "UInt64"}},
+			tables:  []*ext.Table{newIDsTable(t, 1)},
+			err:     `external table "ids": expected columns [{id UInt64}], got [{id UInt32}]`,
+		},
+		{
+			name:   "row count",
+			rows:   [][]any{{uint32(1)}},
+			tables: []*ext.Table{newIDsTable(t, 1, 2)},
+			err:    `external table "ids": expected 1 rows, got 2`,
+		},
+		{
+			name:   "value",
+			rows:   [][]any{{uint32(1)}, {uint32(3)}},
+			tables: []*ext.Table{newIDsTable(t, 1, 2)},
+			err:    `external table "ids": row 1: column 0: expected 3 (uint32), got 2 (uint32)`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mock, err := NewClickHouseNative(nil)
+			if err != nil {
+				t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			mock.ExpectSelect("SELECT title FROM articles WHERE id IN ids").
+				WithExternalTable("ids", tc.columns, tc.rows)
+
+			ctx := clickhouse.Context(context.Background(), clickhouse.WithExternalTable(tc.tables...))
+			var titles []struct {
+				Title string `ch:"title"`
+			}
+			err = mock.Select(ctx, &titles, "SELECT title FROM articles WHERE id IN ids")
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.err)
+			}
+		})
+	}
+}
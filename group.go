@@ -0,0 +1,85 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import "fmt"
+
+// Expectation is implemented by every Expected* type
+// returned by the Expect methods of the mock.
+type Expectation interface {
+	expectation
+}
+
+// group is a set of expectations sharing an ordering rule,
+// created by InOrder and AnyOrder.
+type group struct {
+	ordered bool
+	members []expectation
+}
+
+// pending returns the first member of the group before ex
+// which was not called enough times yet, or nil if ex is due.
+func (g *group) pending(ex expectation) expectation {
+	for _, member := range g.members {
+		if member == ex {
+			return nil
+		}
+		member.Lock()
+		fulfilled := member.fulfilled()
+		member.Unlock()
+		if !fulfilled {
+			return member
+		}
+	}
+	return nil
+}
+
+func (c *clickhousemock) newGroup(ordered bool, exps []Expectation) {
+	c.expectedMu.Lock()
+	defer c.expectedMu.Unlock()
+
+	g := &group{ordered: ordered}
+	for _, ex := range exps {
+		ce := ex.common()
+		if ce.group != nil {
+			msg := fmt.Sprintf("expectation %s already belongs to a group", ex)
+			if c.t == nil {
+				panic(msg)
+			}
+			c.t.Helper()
+			c.t.Fatalf("%s", msg)
+			return
+		}
+		ce.group = g
+		g.members = append(g.members, ex)
+	}
+}
+
+// InOrder expects the given expectations to be matched in the given
+// order. Calls matching expectations outside of the group may happen
+// at any point in between, regardless of MatchExpectationsInOrder.
+// An expectation is done once it was called as many times as it is
+// expected to be at least, so the next one may be matched after a single
+// call of an expectation set with AtLeast(1), while further calls may
+// still match the former until it is exhausted.
+// An expectation may belong to a single group only.
+func (c *clickhousemock) InOrder(exps ...Expectation) {
+	c.newGroup(true, exps)
+}
+
+// AnyOrder allows the given expectations to be matched in any
+// order, even if the mock matches expectations in order.
+// An expectation may belong to a single group only.
+func (c *clickhousemock) AnyOrder(exps ...Expectation) {
+	c.newGroup(false, exps)
+}
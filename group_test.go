@@ -0,0 +1,131 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInOrderGroupWithInterleavedCalls(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.InOrder(
+		mock.ExpectExec("CREATE TABLE events (id UInt64) ENGINE = MergeTree ORDER BY id"),
+		mock.ExpectPrepareBatch("INSERT INTO events"),
+		mock.ExpectExec("OPTIMIZE TABLE events FINAL"),
+	)
+	mock.AnyOrder(
+		mock.ExpectQuery("SELECT 1").AnyTimes(),
+		mock.ExpectServerVersion().AnyTimes(),
+	)
+
+	ctx := context.Background()
+	_, err = mock.Query(ctx, "SELECT 1")
+	assert.NoError(t, err)
+
+	err = mock.Exec(ctx, "OPTIMIZE TABLE events FINAL")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "was not expected yet, next expectation in its group is")
+	}
+
+	assert.NoError(t, mock.Exec(ctx, "CREATE TABLE events (id UInt64) ENGINE = MergeTree ORDER BY id"))
+	_, err = mock.ServerVersion()
+	assert.NoError(t, err)
+	_, err = mock.PrepareBatch(ctx, "INSERT INTO events")
+	assert.NoError(t, err)
+	_, err = mock.Query(ctx, "SELECT 1")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.Exec(ctx, "OPTIMIZE TABLE events FINAL"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGroupsWithUngroupedExpectations(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectExec("TRUNCATE TABLE a")
+	mock.ExpectExec("TRUNCATE TABLE b")
+	mock.AnyOrder(
+		mock.ExpectQuery("SELECT count() FROM a"),
+		mock.ExpectQuery("SELECT count() FROM b"),
+	)
+
+	ctx := context.Background()
+	_, err = mock.Query(ctx, "SELECT count() FROM b")
+	assert.NoError(t, err)
+
+	// ungrouped expectations keep their global ordering
+	assert.Error(t, mock.Exec(ctx, "TRUNCATE TABLE b"))
+	assert.NoError(t, mock.Exec(ctx, "TRUNCATE TABLE a"))
+
+	_, err = mock.Query(ctx, "SELECT count() FROM a")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.Exec(ctx, "TRUNCATE TABLE b"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpectationInTwoGroupsPanics(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	ex := mock.ExpectPing()
+	mock.InOrder(ex)
+	assert.Panics(t, func() { mock.AnyOrder(ex) })
+}
+
+func TestExpectationInTwoGroupsFailsTest(t *testing.T) {
+	t.Parallel()
+	tb := &recordingTB{TB: t}
+	mock := NewClickHouseNativeT(tb, nil)
+
+	ex := mock.ExpectPing().Maybe()
+	mock.InOrder(ex)
+	mock.AnyOrder(ex)
+	if assert.Len(t, tb.errors, 1) {
+		assert.Contains(t, tb.errors[0], "already belongs to a group")
+	}
+}
+
+func TestInOrderAtLeastOnce(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.InOrder(
+		mock.ExpectExec("INSERT INTO events VALUES (1)").AtLeast(1),
+		mock.ExpectExec("OPTIMIZE TABLE events FINAL"),
+	)
+
+	ctx := context.Background()
+	assert.NoError(t, mock.Exec(ctx, "INSERT INTO events VALUES (1)"))
+	assert.NoError(t, mock.Exec(ctx, "OPTIMIZE TABLE events FINAL"))
+	assert.NoError(t, mock.Exec(ctx, "INSERT INTO events VALUES (1)"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
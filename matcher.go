@@ -0,0 +1,145 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// QueryMatcher matches the SQL of a call against the SQL of an expectation.
+// It is satisfied by the matchers of go-sqlmock as well, so they can be
+// used in place of the ones of this package.
+type QueryMatcher interface {
+	// Match returns an error if actualSQL does not match expectedSQL.
+	Match(expectedSQL, actualSQL string) error
+}
+
+// QueryMatcherFunc is an adapter to allow the use of
+// ordinary functions as QueryMatcher.
+type QueryMatcherFunc func(expectedSQL, actualSQL string) error
+
+// Match implements the QueryMatcher
+func (f QueryMatcherFunc) Match(expectedSQL, actualSQL string) error {
+	return f(expectedSQL, actualSQL)
+}
+
+var spaces = regexp.MustCompile(`\s+`)
+
+// stripQuery collapses the whitespace of q.
+func stripQuery(q string) string {
+	return strings.TrimSpace(spaces.ReplaceAllString(q, " "))
+}
+
+// MatchEqual is the QueryMatcher used by NewClickHouseNative. It requires
+// expected and actual SQL to be equal once their whitespace is collapsed.
+var MatchEqual QueryMatcher = QueryMatcherFunc(func(expectedSQL, actualSQL string) error {
+	expect := stripQuery(expectedSQL)
+	actual := stripQuery(actualSQL)
+	if actual != expect {
+		return fmt.Errorf(`actual sql: "%s" does not equal to expected "%s"`, actual, expect)
+	}
+	return nil
+})
+
+// MatchRegexp is a QueryMatcher compiling the expected SQL,
+// with its whitespace collapsed, to a regular expression
+// which the actual SQL has to match.
+var MatchRegexp QueryMatcher = QueryMatcherFunc(func(expectedSQL, actualSQL string) error {
+	expect := stripQuery(expectedSQL)
+	actual := stripQuery(actualSQL)
+	re, err := regexp.Compile(expect)
+	if err != nil {
+		return err
+	}
+	if !re.MatchString(actual) {
+		return fmt.Errorf(`could not match actual sql: "%s" with expected regexp "%s"`, actual, re.String())
+	}
+	return nil
+})
+
+// MatchNormalized is a QueryMatcher comparing ClickHouse SQL statements
+// token by token, so whitespace, comments and the case of keywords do not
+// matter, while identifiers, string literals and numbers must be equal. Use it
+// with NewClickHouseWithQueryMatcher:
+//
+//	mock, err := mockhouse.NewClickHouseWithQueryMatcher(nil, mockhouse.MatchNormalized)
+var MatchNormalized QueryMatcher = QueryMatcherFunc(func(expectedSQL, actualSQL string) error {
+	expected, actual := normalize(tokenize(expectedSQL)), normalize(tokenize(actualSQL))
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		if i >= len(expected) || i >= len(actual) || !sameToken(expected[i], actual[i]) {
+			return fmt.Errorf(`actual sql: "%s" does not equal to expected "%s" at %s`,
+				actualSQL, expectedSQL, tokenAt(actual, i))
+		}
+	}
+	return nil
+})
+
+// normalize drops the comments of tokens.
+func normalize(tokens []token) []token {
+	normalized := tokens[:0:0]
+	for _, t := range tokens {
+		if t.kind != tokenComment {
+			normalized = append(normalized, t)
+		}
+	}
+	return normalized
+}
+
+// sameToken compares two tokens, ignoring the case of keywords.
+func sameToken(a, b token) bool {
+	if a.kind != b.kind {
+		return false
+	}
+	if a.text == b.text {
+		return true
+	}
+	return a.kind == tokenWord && isKeyword(a.text) && strings.EqualFold(a.text, b.text)
+}
+
+// tokenAt describes the position of the i-th token for mismatch errors.
+func tokenAt(tokens []token, i int) string {
+	if i >= len(tokens) {
+		return "end of statement"
+	}
+	return fmt.Sprintf("token %d %q", i, tokens[i].text)
+}
+
+func isKeyword(word string) bool {
+	_, ok := keywords[strings.ToUpper(word)]
+	return ok
+}
+
+// keywords are the ClickHouse SQL keywords whose case is insignificant.
+var keywords = func() map[string]struct{} {
+	words := strings.Fields(`
+		ADD AFTER ALIAS ALL ALTER AND ANTI ANY ARRAY AS ASC ASOF ATTACH
+		BETWEEN BOTH BY CASE CAST CHECK CLEAR CLUSTER CODEC COLLATE COLUMN
+		COMMENT CONSTRAINT CREATE CROSS CUBE DATABASE DATABASES DEFAULT
+		DELETE DESC DESCENDING DESCRIBE DETACH DICTIONARY DISTINCT DROP ELSE
+		END ENGINE EXCEPT EXCHANGE EXISTS EXPLAIN FINAL FIRST FORMAT FREEZE
+		FROM FULL GLOBAL GRANULARITY GROUP HAVING IF ILIKE IN INDEX INNER
+		INSERT INTERSECT INTERVAL INTO IS JOIN KEY KILL LAST LEFT LIKE LIMIT
+		LIVE LOCAL MATERIALIZE MATERIALIZED MODIFY MOVE NOT NULL NULLS OFFSET
+		ON OPTIMIZE OR ORDER OUTER OUTFILE PARTITION PREWHERE PRIMARY
+		PROJECTION RENAME REPLACE RIGHT ROLLUP SAMPLE SELECT SEMI SET SETTINGS
+		SHOW SYSTEM TABLE TABLES TEMPORARY THEN TIES TO TOP TOTALS TRUNCATE
+		TTL UNION UPDATE USE USING VALUES VIEW WHEN WHERE WITH
+	`)
+	m := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		m[w] = struct{}{}
+	}
+	return m
+}()
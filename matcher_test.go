@@ -0,0 +1,131 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchNormalized(t *testing.T) {
+	t.Parallel()
+	const expected = "SELECT id, title FROM articles WHERE status = 'draft' ORDER BY id LIMIT 10"
+	for _, tc := range []struct {
+		actual string
+		match  bool
+	}{
+		{expected, true},
+		{"select id,title\n  from articles\n where status='draft'\n order by id\n limit 10", true},
+		{"SELECT id, title -- columns\nFROM articles /* all of them */ WHERE status = 'draft' ORDER BY id LIMIT 10", true},
+		{"SELECT ID, title FROM articles WHERE status = 'draft' ORDER BY id LIMIT 10", false},
+		{"SELECT id, title FROM Articles WHERE status = 'draft' ORDER BY id LIMIT 10", false},
+		{"SELECT id, title FROM articles WHERE status = 'Draft' ORDER BY id LIMIT 10", false},
+		{"SELECT id, title FROM articles WHERE status = ' draft' ORDER BY id LIMIT 10", false},
+		{"SELECT id, title FROM articles WHERE status = 'draft' ORDER BY id LIMIT 100", false},
+		{"SELECT id, title FROM articles WHERE status = 'draft' ORDER BY id", false},
+		{"SELECT id, title FROM `articles` WHERE status = 'draft' ORDER BY id LIMIT 10", false},
+	} {
+		err := MatchNormalized.Match(expected, tc.actual)
+		if tc.match {
+			assert.NoError(t, err, tc.actual)
+		} else {
+			assert.Error(t, err, tc.actual)
+		}
+	}
+}
+
+func TestMatchNormalizedQuotedIdentifiers(t *testing.T) {
+	t.Parallel()
+	assert.NoError(t, MatchNormalized.Match("SELECT `user id` FROM \"my table\"", "select  `user id`\nfrom \"my table\""))
+	assert.Error(t, MatchNormalized.Match("SELECT `user id` FROM t", "SELECT `user  id` FROM t"))
+	assert.Error(t, MatchNormalized.Match("SELECT -- 'a'\n1", "SELECT 'a'"))
+}
+
+func TestMatchNormalizedError(t *testing.T) {
+	t.Parallel()
+	err := MatchNormalized.Match("SELECT id FROM jobs", "SELECT id FROM job")
+	assert.EqualError(t, err, `actual sql: "SELECT id FROM job" does not equal to expected "SELECT id FROM jobs" at token 3 "job"`)
+
+	err = MatchNormalized.Match("SELECT id FROM jobs", "SELECT id")
+	assert.EqualError(t, err, `actual sql: "SELECT id" does not equal to expected "SELECT id FROM jobs" at end of statement`)
+}
+
+func TestMockWithMatchNormalized(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseWithQueryMatcher(nil, MatchNormalized)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectExec("ALTER TABLE articles DELETE WHERE id = ?").WithArgs(1)
+
+	err = mock.Exec(context.Background(), `
+		alter table articles
+		delete where id = ? -- soft deletes are handled elsewhere
+	`, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMatchEqualAndRegexp(t *testing.T) {
+	t.Parallel()
+	assert.NoError(t, MatchEqual.Match("SELECT  id\nFROM jobs", " SELECT id FROM jobs "))
+	assert.EqualError(t, MatchEqual.Match("SELECT id FROM jobs", "select id from jobs"),
+		`actual sql: "select id from jobs" does not equal to expected "SELECT id FROM jobs"`)
+
+	assert.NoError(t, MatchRegexp.Match(`SELECT .+ FROM jobs`, "SELECT id, title\nFROM jobs WHERE id = 1"))
+	assert.EqualError(t, MatchRegexp.Match(`^SELECT \w+ FROM jobs$`, "SELECT id FROM job"),
+		`could not match actual sql: "SELECT id FROM job" with expected regexp "^SELECT \w+ FROM jobs$"`)
+	assert.Error(t, MatchRegexp.Match(`SELECT (`, "SELECT ("))
+}
+
+// prefixMatcher has the method set of the matchers of go-sqlmock
+// without being one of the matchers of this package.
+type prefixMatcher struct{}
+
+func (prefixMatcher) Match(expectedSQL, actualSQL string) error {
+	if len(actualSQL) < len(expectedSQL) || actualSQL[:len(expectedSQL)] != expectedSQL {
+		return fmt.Errorf("%q does not start with %q", actualSQL, expectedSQL)
+	}
+	return nil
+}
+
+func TestWithMatcherOverridesMockMatcher(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectExec("TRUNCATE TABLE jobs")
+	mock.ExpectQuery(`SELECT id FROM jobs WHERE id IN \(.+\)`).WithMatcher(MatchRegexp)
+	mock.ExpectSelect("SELECT count() FROM jobs").WithMatcher(prefixMatcher{})
+	mock.ExpectPrepareBatch("insert into jobs").WithMatcher(MatchNormalized)
+	mock.ExpectExec("TRUNCATE TABLE jobs")
+
+	ctx := context.Background()
+	assert.NoError(t, mock.Exec(ctx, "TRUNCATE TABLE jobs"))
+	_, err = mock.Query(ctx, "SELECT id FROM jobs WHERE id IN (1, 2, 3)")
+	assert.NoError(t, err)
+	var count []uint64
+	assert.NoError(t, mock.Select(ctx, &count, "SELECT count() FROM jobs WHERE status = 'done'"))
+	_, err = mock.PrepareBatch(ctx, "INSERT INTO jobs")
+	assert.NoError(t, err)
+	assert.Error(t, mock.Exec(ctx, "TRUNCATE TABLE  jobs;"), "other expectations keep matching exactly")
+	assert.NoError(t, mock.Exec(ctx, "TRUNCATE TABLE jobs"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,153 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// QueryParameter describes a server-side query parameter declared
+// in the SQL with the {name:Type} syntax.
+type QueryParameter struct {
+	// Name of the parameter
+	Name string
+	// Type is the ClickHouse type the query declares for the parameter.
+	// It is not checked when empty.
+	Type string
+	// Value is matched against the value supplied by the caller, either
+	// the string set with clickhouse.WithParameters or the value of a
+	// named argument. An Argument can be used to match it.
+	Value any
+}
+
+// Param returns a QueryParameter expecting the query to declare
+// {name:chType} and the caller to supply a value matching value.
+func Param(name, chType string, value any) QueryParameter {
+	return QueryParameter{Name: name, Type: chType, Value: value}
+}
+
+var queryParameterRe = regexp.MustCompile(`\{\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*:\s*([^{}]+?)\s*\}`)
+
+// declaredParameters returns the {name:Type} parameters declared in query
+// keyed by name.
+func declaredParameters(query string) map[string]string {
+	declared := make(map[string]string)
+	for _, m := range queryParameterRe.FindAllStringSubmatch(query, -1) {
+		declared[m[1]] = m[2]
+	}
+	return declared
+}
+
+// namedArgs returns the driver.NamedValue and driver.NamedDateValue
+// arguments keyed by name.
+func namedArgs(args []any) map[string]any {
+	named := make(map[string]any)
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case driver.NamedValue:
+			named[v.Name] = v.Value
+		case driver.NamedDateValue:
+			named[v.Name] = v.Value
+		}
+	}
+	return named
+}
+
+func (e *queryBasedExpectation) matchNamedArgs(args []any) error {
+	if e.namedArgs == nil {
+		return nil
+	}
+
+	actual := namedArgs(args)
+	for _, expected := range e.namedArgs {
+		v, ok := actual[expected.Name]
+		if !ok {
+			return fmt.Errorf("named argument %q was not passed", expected.Name)
+		}
+		if err := matchArg(expected.Value, v); err != nil {
+			return fmt.Errorf("named argument %q: %s", expected.Name, err)
+		}
+		delete(actual, expected.Name)
+	}
+	if name, ok := firstKey(actual); ok {
+		return fmt.Errorf("named argument %q was not expected", name)
+	}
+	return nil
+}
+
+func (e *queryBasedExpectation) matchParameters(cl *call) error {
+	if e.params == nil {
+		return nil
+	}
+
+	declared := declaredParameters(cl.query)
+	supplied := make(map[string]any)
+	if params := queryOptionsFromContext(cl.ctx).parameters; len(params) > 0 {
+		for name, v := range params {
+			supplied[name] = v
+		}
+	} else if len(declared) > 0 {
+		// like clickhouse-go, fall back to sending named arguments
+		// as parameters when the query declares any
+		supplied = namedArgs(cl.args)
+	}
+
+	for _, expected := range e.params {
+		typ, ok := declared[expected.Name]
+		if !ok {
+			return fmt.Errorf("parameter %q is not declared in the query", expected.Name)
+		}
+		if expected.Type != "" && compactType(typ) != compactType(expected.Type) {
+			return fmt.Errorf("parameter %q is declared as %s, expected %s", expected.Name, typ, expected.Type)
+		}
+		v, ok := supplied[expected.Name]
+		if !ok {
+			return fmt.Errorf("parameter %q was not supplied", expected.Name)
+		}
+		if err := matchArg(expected.Value, v); err != nil {
+			return fmt.Errorf("parameter %q: %s", expected.Name, err)
+		}
+		delete(declared, expected.Name)
+		delete(supplied, expected.Name)
+	}
+	if name, ok := firstKey(declared); ok {
+		return fmt.Errorf("parameter %q is declared in the query but was not expected", name)
+	}
+	if name, ok := firstKey(supplied); ok {
+		return fmt.Errorf("parameter %q was supplied but not expected", name)
+	}
+	return nil
+}
+
+// compactType drops the whitespace of a ClickHouse type name
+// so that e.g. Map(String, UInt64) equals Map(String,UInt64)
+func compactType(typ string) string {
+	return strings.Join(strings.Fields(typ), "")
+}
+
+func firstKey[T any](m map[string]T) (string, bool) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return "", false
+	}
+	sort.Strings(keys)
+	return keys[0], true
+}
@@ -0,0 +1,125 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamedArgs(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.MatchExpectationsInOrder(false)
+
+	query := "SELECT * FROM events WHERE tenant = @tenant AND ts > @ts"
+	mock.ExpectQuery(query).WithNamedArgs(
+		clickhouse.Named("tenant", "acme"),
+		clickhouse.Named("ts", OfType(time.Time{})),
+	)
+
+	ctx := context.Background()
+	_, err = mock.Query(ctx, query, clickhouse.Named("tenant", "other"), clickhouse.DateNamed("ts", time.Now(), clickhouse.Seconds))
+	assert.Error(t, err)
+
+	_, err = mock.Query(ctx, query, clickhouse.Named("tenant", "acme"))
+	assert.Error(t, err)
+
+	_, err = mock.Query(ctx, query, clickhouse.Named("tenant", "acme"), clickhouse.DateNamed("ts", time.Now(), clickhouse.Seconds))
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestServerSideParameters(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.MatchExpectationsInOrder(false)
+
+	query := "SELECT * FROM events WHERE tenant = {tenant:String} AND id IN {ids:Array(UInt64)}"
+	mock.ExpectSelect(query).WithParameters(
+		Param("tenant", "String", "acme"),
+		Param("ids", "Array( UInt64 )", AnyArg()),
+	)
+	mock.ExpectExec(query).WithParameters(
+		Param("tenant", "String", "acme"),
+		Param("ids", "Array(UInt32)", AnyArg()),
+	)
+	mock.ExpectQueryRow(query).WithParameters(
+		Param("tenant", "String", "acme"),
+		Param("ids", "", "[1,2]"),
+	)
+
+	var dest []struct{}
+	ctx := clickhouse.Context(context.Background(), clickhouse.WithParameters(clickhouse.Parameters{
+		"tenant": "acme",
+		"ids":    "[1,2]",
+	}))
+	assert.NoError(t, mock.Select(ctx, &dest, query))
+
+	assert.Error(t, mock.Exec(ctx, query))
+
+	// named arguments are sent as parameters when the context has none
+	row := mock.QueryRow(context.Background(), query, clickhouse.Named("tenant", "acme"), clickhouse.Named("ids", "[1,2]"))
+	assert.NoError(t, row.Err())
+}
+
+func TestServerSideParametersFromNamedArgs(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	query := "SELECT * FROM events WHERE tenant = {tenant:String} LIMIT {limit:UInt8}"
+	mock.ExpectQuery(query).WithParameters(
+		Param("tenant", "String", "acme"),
+		Param("limit", "UInt8", 10),
+	)
+	mock.ExpectQuery(query).WithParameters(
+		Param("tenant", "String", "acme"),
+		Param("limit", "UInt8", 10),
+	)
+
+	_, err = mock.Query(context.Background(), query, clickhouse.Named("tenant", "acme"), clickhouse.Named("limit", 10))
+	assert.NoError(t, err)
+
+	_, err = mock.Query(context.Background(), query, clickhouse.Named("tenant", "acme"))
+	assert.EqualError(t, err, "Query: '"+query+"' parameters do not match: parameter \"limit\" was not supplied")
+}
+
+func TestServerSideParameterTypeMismatch(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	query := "ALTER TABLE events DELETE WHERE id IN {ids:Array(UInt64)}"
+	mock.ExpectExec(query).WithParameters(Param("ids", "Array(UInt32)", AnyArg()))
+
+	ctx := clickhouse.Context(context.Background(), clickhouse.WithParameters(clickhouse.Parameters{"ids": "[1,2]"}))
+	err = mock.Exec(ctx, query)
+	assert.EqualError(t, err, "Exec: '"+query+"' parameters do not match: parameter \"ids\" is declared as Array(UInt64), expected Array(UInt32)")
+}
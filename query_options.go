@@ -0,0 +1,124 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"reflect"
+	"time"
+	"unsafe"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/ext"
+)
+
+// contextOptionKey is the key clickhouse.Context stores clickhouse.QueryOptions
+// under. The driver does not export it, so it is taken from a probe context.
+var contextOptionKey = func() any {
+	v := reflect.ValueOf(clickhouse.Context(context.Background()))
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	key := v.Elem().FieldByName("key")
+	if !key.IsValid() {
+		return nil
+	}
+	return unexported(key).Interface()
+}()
+
+// queryOptions holds the options set on a context with clickhouse.Context
+// which the mock is able to verify.
+type queryOptions struct {
+	queryID      string
+	quotaKey     string
+	jwt          string
+	settings     clickhouse.Settings
+	parameters   clickhouse.Parameters
+	userLocation *time.Location
+	external     []*ext.Table
+	// async and asyncWait are set by clickhouse.WithAsync
+	async     bool
+	asyncWait bool
+
+	// the callbacks registered with clickhouse.WithLogs, clickhouse.WithProgress,
+	// clickhouse.WithProfileInfo and clickhouse.WithProfileEvents
+	logs          func(*clickhouse.Log)
+	progress      func(*clickhouse.Progress)
+	profileInfo   func(*clickhouse.ProfileInfo)
+	profileEvents func([]clickhouse.ProfileEvent)
+}
+
+// queryOptionsFromContext reads the clickhouse.QueryOptions of ctx. The driver
+// keeps all of their fields unexported, so they are read through reflection.
+func queryOptionsFromContext(ctx context.Context) queryOptions {
+	var o queryOptions
+	if ctx == nil || contextOptionKey == nil {
+		return o
+	}
+	opts, ok := ctx.Value(contextOptionKey).(clickhouse.QueryOptions)
+	if !ok {
+		return o
+	}
+
+	v := reflect.ValueOf(&opts).Elem()
+	if f := v.FieldByName("queryID"); f.IsValid() {
+		o.queryID, _ = unexported(f).Interface().(string)
+	}
+	if f := v.FieldByName("quotaKey"); f.IsValid() {
+		o.quotaKey, _ = unexported(f).Interface().(string)
+	}
+	if f := v.FieldByName("jwt"); f.IsValid() {
+		o.jwt, _ = unexported(f).Interface().(string)
+	}
+	if f := v.FieldByName("userLocation"); f.IsValid() {
+		o.userLocation, _ = unexported(f).Interface().(*time.Location)
+	}
+	if f := v.FieldByName("settings"); f.IsValid() {
+		o.settings, _ = unexported(f).Interface().(clickhouse.Settings)
+	}
+	if f := v.FieldByName("parameters"); f.IsValid() {
+		o.parameters, _ = unexported(f).Interface().(clickhouse.Parameters)
+	}
+	if f := v.FieldByName("external"); f.IsValid() {
+		o.external, _ = unexported(f).Interface().([]*ext.Table)
+	}
+	if async := v.FieldByName("async"); async.IsValid() {
+		if f := async.FieldByName("ok"); f.Kind() == reflect.Bool {
+			o.async = f.Bool()
+		}
+		if f := async.FieldByName("wait"); f.Kind() == reflect.Bool {
+			o.asyncWait = f.Bool()
+		}
+	}
+	if events := v.FieldByName("events"); events.IsValid() {
+		if f := events.FieldByName("logs"); f.IsValid() {
+			o.logs, _ = unexported(f).Interface().(func(*clickhouse.Log))
+		}
+		if f := events.FieldByName("progress"); f.IsValid() {
+			o.progress, _ = unexported(f).Interface().(func(*clickhouse.Progress))
+		}
+		if f := events.FieldByName("profileInfo"); f.IsValid() {
+			o.profileInfo, _ = unexported(f).Interface().(func(*clickhouse.ProfileInfo))
+		}
+		if f := events.FieldByName("profileEvents"); f.IsValid() {
+			o.profileEvents, _ = unexported(f).Interface().(func([]clickhouse.ProfileEvent))
+		}
+	}
+	return o
+}
+
+// unexported makes the value of an unexported, addressable
+// struct field accessible through Interface.
+func unexported(field reflect.Value) reflect.Value {
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+}
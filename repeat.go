@@ -0,0 +1,586 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import "fmt"
+
+// unlimited is the maximum number of calls of an expectation
+// which may be matched any number of times, times rejects
+// negative counts so it can't be set by mistake
+const unlimited = -1
+
+// repeat keeps track of how many times an expectation
+// is expected to be matched and how many times it was.
+// The zero value expects exactly one call.
+type repeat struct {
+	calls   int
+	atLeast int
+	atMost  int
+	set     bool
+}
+
+func (r *repeat) min() int {
+	if !r.set {
+		return 1
+	}
+	return r.atLeast
+}
+
+func (r *repeat) max() int {
+	if !r.set {
+		return 1
+	}
+	return r.atMost
+}
+
+func (r *repeat) times(atLeast, atMost int) {
+	if atLeast < 0 {
+		panic(fmt.Sprintf("expected number of calls must not be negative, got %d", atLeast))
+	}
+	r.set = true
+	r.atLeast = atLeast
+	r.atMost = atMost
+}
+
+// fulfilled reports whether the expectation was matched
+// at least as many times as it is expected to be.
+func (r *repeat) fulfilled() bool {
+	return r.calls >= r.min()
+}
+
+// exhausted reports whether the expectation may not be matched anymore.
+func (r *repeat) exhausted() bool {
+	return r.max() != unlimited && r.calls >= r.max()
+}
+
+// trigger records a match of the expectation.
+func (r *repeat) trigger() {
+	r.calls++
+}
+
+// callsString describes the expected and actual number of calls,
+// it is empty for expectations expected to be called once.
+func (r *repeat) callsString() string {
+	if !r.set {
+		return ""
+	}
+	return fmt.Sprintf("should be called %s, was called %s", r.expectedCalls(), pluralTimes(r.calls))
+}
+
+// callsSuffix is callsString formatted for short string representations
+func (r *repeat) callsSuffix() string {
+	if calls := r.callsString(); calls != "" {
+		return " [" + calls + "]"
+	}
+	return ""
+}
+
+func (r *repeat) expectedCalls() string {
+	atLeast, atMost := r.min(), r.max()
+	switch {
+	case atLeast == 0 && atMost == unlimited:
+		return "any number of times"
+	case atMost == unlimited:
+		return "at least " + pluralTimes(atLeast)
+	case atLeast == atMost:
+		return "exactly " + pluralTimes(atLeast)
+	case atLeast == 0:
+		return "at most " + pluralTimes(atMost)
+	}
+	return fmt.Sprintf("between %d and %s", atLeast, pluralTimes(atMost))
+}
+
+func pluralTimes(n int) string {
+	switch n {
+	case 1:
+		return "once"
+	case 2:
+		return "twice"
+	}
+	return fmt.Sprintf("%d times", n)
+}
+
+// Times expects the Close action to be called exactly n times.
+func (e *ExpectedClose) Times(n int) *ExpectedClose {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the Close action to be called n or more times.
+func (e *ExpectedClose) AtLeast(n int) *ExpectedClose {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the Close action to be called any number of times, including never.
+func (e *ExpectedClose) AnyTimes() *ExpectedClose {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the Close action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedClose) Maybe() *ExpectedClose {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the Query action to be called exactly n times.
+func (e *ExpectedQuery) Times(n int) *ExpectedQuery {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the Query action to be called n or more times.
+func (e *ExpectedQuery) AtLeast(n int) *ExpectedQuery {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the Query action to be called any number of times, including never.
+func (e *ExpectedQuery) AnyTimes() *ExpectedQuery {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the Query action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedQuery) Maybe() *ExpectedQuery {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the Exec action to be called exactly n times.
+func (e *ExpectedExec) Times(n int) *ExpectedExec {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the Exec action to be called n or more times.
+func (e *ExpectedExec) AtLeast(n int) *ExpectedExec {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the Exec action to be called any number of times, including never.
+func (e *ExpectedExec) AnyTimes() *ExpectedExec {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the Exec action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedExec) Maybe() *ExpectedExec {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the Ping action to be called exactly n times.
+func (e *ExpectedPing) Times(n int) *ExpectedPing {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the Ping action to be called n or more times.
+func (e *ExpectedPing) AtLeast(n int) *ExpectedPing {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the Ping action to be called any number of times, including never.
+func (e *ExpectedPing) AnyTimes() *ExpectedPing {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the Ping action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedPing) Maybe() *ExpectedPing {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the PrepareBatch action to be called exactly n times.
+func (e *ExpectedPrepareBatch) Times(n int) *ExpectedPrepareBatch {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the PrepareBatch action to be called n or more times.
+func (e *ExpectedPrepareBatch) AtLeast(n int) *ExpectedPrepareBatch {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the PrepareBatch action to be called any number of times, including never.
+func (e *ExpectedPrepareBatch) AnyTimes() *ExpectedPrepareBatch {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the PrepareBatch action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedPrepareBatch) Maybe() *ExpectedPrepareBatch {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the Abort action to be called exactly n times.
+func (e *ExpectedAbort) Times(n int) *ExpectedAbort {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the Abort action to be called n or more times.
+func (e *ExpectedAbort) AtLeast(n int) *ExpectedAbort {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the Abort action to be called any number of times, including never.
+func (e *ExpectedAbort) AnyTimes() *ExpectedAbort {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the Abort action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedAbort) Maybe() *ExpectedAbort {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the Append action to be called exactly n times.
+func (e *ExpectedAppend) Times(n int) *ExpectedAppend {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the Append action to be called n or more times.
+func (e *ExpectedAppend) AtLeast(n int) *ExpectedAppend {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the Append action to be called any number of times, including never.
+func (e *ExpectedAppend) AnyTimes() *ExpectedAppend {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the Append action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedAppend) Maybe() *ExpectedAppend {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the AppendStruct action to be called exactly n times.
+func (e *ExpectedAppendStruct) Times(n int) *ExpectedAppendStruct {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the AppendStruct action to be called n or more times.
+func (e *ExpectedAppendStruct) AtLeast(n int) *ExpectedAppendStruct {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the AppendStruct action to be called any number of times, including never.
+func (e *ExpectedAppendStruct) AnyTimes() *ExpectedAppendStruct {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the AppendStruct action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedAppendStruct) Maybe() *ExpectedAppendStruct {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the Column action to be called exactly n times.
+func (e *ExpectedColumn) Times(n int) *ExpectedColumn {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the Column action to be called n or more times.
+func (e *ExpectedColumn) AtLeast(n int) *ExpectedColumn {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the Column action to be called any number of times, including never.
+func (e *ExpectedColumn) AnyTimes() *ExpectedColumn {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the Column action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedColumn) Maybe() *ExpectedColumn {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the Flush action to be called exactly n times.
+func (e *ExpectedFlush) Times(n int) *ExpectedFlush {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the Flush action to be called n or more times.
+func (e *ExpectedFlush) AtLeast(n int) *ExpectedFlush {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the Flush action to be called any number of times, including never.
+func (e *ExpectedFlush) AnyTimes() *ExpectedFlush {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the Flush action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedFlush) Maybe() *ExpectedFlush {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the Send action to be called exactly n times.
+func (e *ExpectedSend) Times(n int) *ExpectedSend {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the Send action to be called n or more times.
+func (e *ExpectedSend) AtLeast(n int) *ExpectedSend {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the Send action to be called any number of times, including never.
+func (e *ExpectedSend) AnyTimes() *ExpectedSend {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the Send action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedSend) Maybe() *ExpectedSend {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the IsSent action to be called exactly n times.
+func (e *ExpectedIsSent) Times(n int) *ExpectedIsSent {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the IsSent action to be called n or more times.
+func (e *ExpectedIsSent) AtLeast(n int) *ExpectedIsSent {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the IsSent action to be called any number of times, including never.
+func (e *ExpectedIsSent) AnyTimes() *ExpectedIsSent {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the IsSent action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedIsSent) Maybe() *ExpectedIsSent {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the Close action to be called exactly n times.
+func (e *ExpectedBatchClose) Times(n int) *ExpectedBatchClose {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the Close action to be called n or more times.
+func (e *ExpectedBatchClose) AtLeast(n int) *ExpectedBatchClose {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the Close action to be called any number of times, including never.
+func (e *ExpectedBatchClose) AnyTimes() *ExpectedBatchClose {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the Close action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedBatchClose) Maybe() *ExpectedBatchClose {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the Stats action to be called exactly n times.
+func (e *ExpectedStats) Times(n int) *ExpectedStats {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the Stats action to be called n or more times.
+func (e *ExpectedStats) AtLeast(n int) *ExpectedStats {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the Stats action to be called any number of times, including never.
+func (e *ExpectedStats) AnyTimes() *ExpectedStats {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the Stats action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedStats) Maybe() *ExpectedStats {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the AsyncInsert action to be called exactly n times.
+func (e *ExpectedAsyncInsert) Times(n int) *ExpectedAsyncInsert {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the AsyncInsert action to be called n or more times.
+func (e *ExpectedAsyncInsert) AtLeast(n int) *ExpectedAsyncInsert {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the AsyncInsert action to be called any number of times, including never.
+func (e *ExpectedAsyncInsert) AnyTimes() *ExpectedAsyncInsert {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the AsyncInsert action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedAsyncInsert) Maybe() *ExpectedAsyncInsert {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the QueryRow action to be called exactly n times.
+func (e *ExpectedQueryRow) Times(n int) *ExpectedQueryRow {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the QueryRow action to be called n or more times.
+func (e *ExpectedQueryRow) AtLeast(n int) *ExpectedQueryRow {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the QueryRow action to be called any number of times, including never.
+func (e *ExpectedQueryRow) AnyTimes() *ExpectedQueryRow {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the QueryRow action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedQueryRow) Maybe() *ExpectedQueryRow {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the Select action to be called exactly n times.
+func (e *ExpectedSelect) Times(n int) *ExpectedSelect {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the Select action to be called n or more times.
+func (e *ExpectedSelect) AtLeast(n int) *ExpectedSelect {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the Select action to be called any number of times, including never.
+func (e *ExpectedSelect) AnyTimes() *ExpectedSelect {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the Select action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedSelect) Maybe() *ExpectedSelect {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the ServerVersion action to be called exactly n times.
+func (e *ExpectedServerVersion) Times(n int) *ExpectedServerVersion {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the ServerVersion action to be called n or more times.
+func (e *ExpectedServerVersion) AtLeast(n int) *ExpectedServerVersion {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the ServerVersion action to be called any number of times, including never.
+func (e *ExpectedServerVersion) AnyTimes() *ExpectedServerVersion {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the ServerVersion action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedServerVersion) Maybe() *ExpectedServerVersion {
+	e.times(0, e.max())
+	return e
+}
+
+// Times expects the Contributors action to be called exactly n times.
+func (e *ExpectedContributors) Times(n int) *ExpectedContributors {
+	e.times(n, n)
+	return e
+}
+
+// AtLeast expects the Contributors action to be called n or more times.
+func (e *ExpectedContributors) AtLeast(n int) *ExpectedContributors {
+	e.times(n, unlimited)
+	return e
+}
+
+// AnyTimes allows the Contributors action to be called any number of times, including never.
+func (e *ExpectedContributors) AnyTimes() *ExpectedContributors {
+	e.times(0, unlimited)
+	return e
+}
+
+// Maybe makes the Contributors action optional, it is not reported
+// by ExpectationsWereMet if it was never called.
+func (e *ExpectedContributors) Maybe() *ExpectedContributors {
+	e.times(0, e.max())
+	return e
+}
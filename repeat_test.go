@@ -0,0 +1,160 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimes(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	rows := NewRows([]ColumnType{{Name: "id", Type: "Int32"}}, [][]any{{int32(1)}, {int32(2)}})
+	mock.ExpectQuery("SELECT id FROM jobs").WillReturnRows(rows).Times(3)
+	mock.ExpectExec("TRUNCATE TABLE jobs")
+
+	for i := 0; i < 3; i++ {
+		returnRows, err := mock.Query(context.Background(), "SELECT id FROM jobs")
+		if !assert.NoError(t, err) {
+			continue
+		}
+		var count int
+		for returnRows.Next() {
+			var id int32
+			assert.NoError(t, returnRows.Scan(&id))
+			count++
+		}
+		assert.Equal(t, 2, count, "every call should read all rows")
+	}
+
+	_, err = mock.Query(context.Background(), "SELECT id FROM jobs")
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.Exec(context.Background(), "TRUNCATE TABLE jobs"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTimesNotMet(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	ex := mock.ExpectExec("OPTIMIZE TABLE jobs").Times(2)
+	assert.NoError(t, mock.Exec(context.Background(), "OPTIMIZE TABLE jobs"))
+
+	assert.Contains(t, ex.String(), "should be called exactly twice, was called once")
+	err = mock.ExpectationsWereMet()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "should be called exactly twice, was called once")
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectPing().AtLeast(2)
+	mock.monitorPings = true
+
+	assert.NoError(t, mock.Ping(context.Background()))
+	assert.Error(t, mock.ExpectationsWereMet())
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, mock.Ping(context.Background()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAnyTimesInOrder(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery("SELECT count() FROM jobs").AnyTimes()
+	mock.ExpectExec("TRUNCATE TABLE jobs")
+
+	for i := 0; i < 3; i++ {
+		_, err := mock.Query(context.Background(), "SELECT count() FROM jobs")
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, mock.Exec(context.Background(), "TRUNCATE TABLE jobs"))
+
+	// an expectation allowed any number of times stays available
+	_, err = mock.Query(context.Background(), "SELECT count() FROM jobs")
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMaybe(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectClose().Maybe()
+	mock.ExpectServerVersion().Maybe()
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = mock.ServerVersion()
+	assert.NoError(t, err)
+	_, err = mock.ServerVersion()
+	assert.Error(t, err)
+}
+
+func TestRepeatString(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		ex       expectation
+		expected string
+	}{
+		{(&ExpectedSelect{}).AnyTimes(), "Select() [should be called any number of times, was called 0 times]"},
+		{(&ExpectedStats{}).AtLeast(3), "Stats() [should be called at least 3 times, was called 0 times]"},
+		{(&ExpectedContributors{}).Maybe(), "Contributors() - Mocked [should be called at most once, was called 0 times]"},
+		{(&ExpectedClose{}).Times(4), "ExpectedClose => expecting database Close, should be called exactly 4 times, was called 0 times"},
+		{&ExpectedClose{}, "ExpectedClose => expecting database Close"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.ex.String())
+	}
+}
+
+func TestNegativeTimes(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	assert.PanicsWithValue(t, "expected number of calls must not be negative, got -1", func() {
+		mock.ExpectExec("TRUNCATE TABLE jobs").Times(-1)
+	})
+	assert.PanicsWithValue(t, "expected number of calls must not be negative, got -2", func() {
+		mock.ExpectPing().AtLeast(-2)
+	})
+}
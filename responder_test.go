@@ -0,0 +1,119 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryWillRespond(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	query := "SELECT id FROM articles ORDER BY id LIMIT 2 OFFSET ?"
+	cols := []ColumnType{{Name: "id", Type: "UInt64"}}
+	mock.ExpectQuery(query).WithAnyArgs().AnyTimes().WillRespond(func(ctx context.Context, query string, args []any) (*Rows, error) {
+		offset := args[0].(int)
+		if offset > 2 {
+			return nil, errors.New("offset out of range")
+		}
+		var values [][]any
+		for id := offset; id < offset+2 && id < 3; id++ {
+			values = append(values, []any{uint64(id)})
+		}
+		return NewRows(cols, values), nil
+	})
+
+	var ids []uint64
+	for offset := 0; ; offset += 2 {
+		rows, err := mock.Query(context.Background(), query, offset)
+		if !assert.NoError(t, err) {
+			break
+		}
+		n := 0
+		for rows.Next() {
+			var id uint64
+			assert.NoError(t, rows.Scan(&id))
+			ids = append(ids, id)
+			n++
+		}
+		if n < 2 {
+			break
+		}
+	}
+	assert.Equal(t, []uint64{0, 1, 2}, ids)
+
+	_, err = mock.Query(context.Background(), query, 4)
+	assert.EqualError(t, err, "offset out of range")
+}
+
+func TestSelectAndQueryRowWillRespond(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	cols := []ColumnType{{Name: "name", Type: "String"}}
+	respond := func(ctx context.Context, query string, args []any) (*Rows, error) {
+		return NewRows(cols, [][]any{{fmt.Sprint(args[0])}}), nil
+	}
+	mock.ExpectSelect("SELECT name FROM users WHERE id = ?").WithAnyArgs().WillRespond(respond)
+	mock.ExpectQueryRow("SELECT name FROM users WHERE id = ?").WithAnyArgs().WillRespond(respond)
+
+	var users []struct {
+		Name string `ch:"name"`
+	}
+	assert.NoError(t, mock.Select(context.Background(), &users, "SELECT name FROM users WHERE id = ?", 7))
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, "7", users[0].Name)
+	}
+
+	var name string
+	assert.NoError(t, mock.QueryRow(context.Background(), "SELECT name FROM users WHERE id = ?", 8).Scan(&name))
+	assert.Equal(t, "8", name)
+}
+
+func TestPrepareBatchWillRespond(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	var sent [][]any
+	mock.ExpectPrepareBatch("INSERT INTO articles").WillRespond(func(ctx context.Context, query string, rows [][]any) error {
+		sent = rows
+		if len(rows) > 1 {
+			return errors.New("too many rows")
+		}
+		return nil
+	})
+
+	batch, err := mock.PrepareBatch(context.Background(), "INSERT INTO articles")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, batch.Append(1, "title"))
+	assert.NoError(t, batch.Append(2, "other"))
+	assert.EqualError(t, batch.Send(), "too many rows")
+	assert.Equal(t, [][]any{{1, "title"}, {2, "other"}}, sent)
+}
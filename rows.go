@@ -41,10 +41,15 @@ type Rows struct {
 	pos       int
 	nextErr   map[int]error
 	closeErr  error
+	emitter   *emitter
 }
 
 func (r *Rows) Next() bool {
-	return r.pos < len(r.values)
+	more := r.pos < len(r.values)
+	if r.emitter != nil {
+		r.emitter.next(r.pos, more)
+	}
+	return more
 }
 
 func (r *Rows) Scan(dest ...any) error {
@@ -87,6 +92,9 @@ func (r *Rows) ColumnTypes() []driver.ColumnType {
 }
 
 func (r *Rows) Close() error {
+	if r.emitter != nil {
+		r.emitter.end()
+	}
 	return r.closeErr
 }
 
@@ -94,6 +102,23 @@ func (r *Rows) Err() error {
 	return nil
 }
 
+// closeRows wraps the rows returned by a call to report when they
+// are first closed, leaving the rows set on the expectation untouched.
+type closeRows struct {
+	*Rows
+	onClose func()
+	closed  bool
+}
+
+func (r *closeRows) Close() error {
+	err := r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		r.onClose()
+	}
+	return err
+}
+
 type Row struct {
 	err  error
 	rows *Rows
@@ -260,16 +285,9 @@ func NewRows(columns []ColumnType, values [][]any, opts ...RowsOption) *Rows {
 		reflectType := getReflectType(string(col.Type))
 		colTypes = append(colTypes, NewColumnType(col.Name, string(col.Type), false, reflectType))
 	}
-	block := &proto.Block{}
-	// Set timezone on block before adding columns
-	block.ServerContext = &column.ServerContext{
-		Timezone: options.timezone,
-	}
-	for _, col := range columns {
-		err := block.AddColumn(col.Name, col.Type)
-		if err != nil {
-			panic(err)
-		}
+	block, err := newBlock(columns, options.timezone)
+	if err != nil {
+		panic(err)
 	}
 	for _, row := range values {
 		err := block.Append(row...)
@@ -286,6 +304,45 @@ func NewRows(columns []ColumnType, values [][]any, opts ...RowsOption) *Rows {
 	}
 }
 
+// newBlock builds a block of the given columns,
+// converting time values to and from timezone.
+func newBlock(columns []ColumnType, timezone *time.Location) (*proto.Block, error) {
+	block := &proto.Block{}
+	// Set timezone on block before adding columns
+	block.ServerContext = &column.ServerContext{
+		Timezone: timezone,
+	}
+	for _, col := range columns {
+		if err := block.AddColumn(col.Name, col.Type); err != nil {
+			return nil, err
+		}
+	}
+	return block, nil
+}
+
+// forCall returns the rows to hand out for the n-th call of a repeated
+// expectation. The first call gets r itself, later calls get a copy
+// positioned at the first row so that every call reads all of them.
+func (r *Rows) forCall(n int) *Rows {
+	if n <= 1 {
+		return r
+	}
+	cp := *r
+	cp.pos = 0
+	return &cp
+}
+
+// withEmitter returns a copy of the rows emitting the events of m
+// while they are iterated, or the rows themselves if m is nil.
+func (r *Rows) withEmitter(m *emitter) *Rows {
+	if m == nil {
+		return r
+	}
+	cp := *r
+	cp.emitter = m
+	return &cp
+}
+
 func NewRow(columns []ColumnType, values []any, opts ...RowsOption) *Row {
 	values2 := make([][]any, 0)
 	if len(values) != 0 {
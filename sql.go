@@ -0,0 +1,157 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenWord       tokenKind = iota // keywords and bare identifiers
+	tokenIdentifier                  // `quoted` or "quoted" identifiers
+	tokenString                      // 'string literals'
+	tokenNumber
+	tokenOperator // operators and punctuation
+	tokenComment  // -- line and /* block */ comments
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// multiCharOperators are the operators of ClickHouse SQL
+// longer than a single character.
+var multiCharOperators = []string{"<=>", "<=", ">=", "!=", "<>", "==", "||", "->", "::"}
+
+// tokenize splits a ClickHouse SQL statement into tokens, dropping
+// whitespace. Comments are kept as tokens of their own, unterminated
+// quotes and comments run to the end of the statement.
+func tokenize(sql string) []token {
+	var tokens []token
+	for i := 0; i < len(sql); {
+		r, size := utf8.DecodeRuneInString(sql[i:])
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			continue
+		case strings.HasPrefix(sql[i:], "--"):
+			i = indexFrom(sql, i, "\n")
+			tokens = append(tokens, token{tokenComment, strings.TrimSpace(sql[start:i])})
+			continue
+		case strings.HasPrefix(sql[i:], "/*"):
+			i = indexFrom(sql, i+2, "*/")
+			if i < len(sql) {
+				i += 2
+			}
+			tokens = append(tokens, token{tokenComment, sql[start:i]})
+			continue
+		case r == '\'':
+			i = quoteEnd(sql, i)
+			tokens = append(tokens, token{tokenString, sql[start:i]})
+			continue
+		case r == '`' || r == '"':
+			i = quoteEnd(sql, i)
+			tokens = append(tokens, token{tokenIdentifier, sql[start:i]})
+			continue
+		case r >= '0' && r <= '9':
+			i = numberEnd(sql, i)
+			tokens = append(tokens, token{tokenNumber, sql[start:i]})
+			continue
+		case isWordRune(r):
+			for i < len(sql) {
+				r, size := utf8.DecodeRuneInString(sql[i:])
+				if !isWordRune(r) && !(r >= '0' && r <= '9') {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, token{tokenWord, sql[start:i]})
+			continue
+		}
+
+		i += size
+		for _, op := range multiCharOperators {
+			if strings.HasPrefix(sql[start:], op) {
+				i = start + len(op)
+				break
+			}
+		}
+		tokens = append(tokens, token{tokenOperator, sql[start:i]})
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r)
+}
+
+// indexFrom returns the index of substr in s at or after i,
+// or len(s) if there is none.
+func indexFrom(s string, i int, substr string) int {
+	if n := strings.Index(s[i:], substr); n >= 0 {
+		return i + n
+	}
+	return len(s)
+}
+
+// quoteEnd returns the index right after the quoted literal starting at i.
+// Quotes are escaped either with a backslash or by doubling them.
+func quoteEnd(s string, i int) int {
+	quote := s[i]
+	for i++; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// numberEnd returns the index right after the numeric literal starting at i,
+// covering decimal and exponent notations, and hexadecimal ones after 0x.
+func numberEnd(s string, i int) int {
+	if strings.HasPrefix(s[i:], "0x") || strings.HasPrefix(s[i:], "0X") {
+		i += 2
+		for i < len(s) && (isHexDigit(s[i]) || s[i] == '_') {
+			i++
+		}
+		return i
+	}
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9', c == '.', c == '_', c == 'e', c == 'E':
+		case (c == '+' || c == '-') && (s[i-1] == 'e' || s[i-1] == 'E'):
+		default:
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
@@ -0,0 +1,76 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+	query := "SELECT `id`, \"user name\", 'it''s \\'ok\\'' -- trailing\n" +
+		"FROM db.t /* block */ WHERE x >= 1.5e-3 AND y != 0x1F AND z = ? AND w = $1 AND {p:UInt8} <> 'open"
+
+	assert.Equal(t, []token{
+		{tokenWord, "SELECT"},
+		{tokenIdentifier, "`id`"},
+		{tokenOperator, ","},
+		{tokenIdentifier, `"user name"`},
+		{tokenOperator, ","},
+		{tokenString, `'it''s \'ok\''`},
+		{tokenComment, "-- trailing"},
+		{tokenWord, "FROM"},
+		{tokenWord, "db"},
+		{tokenOperator, "."},
+		{tokenWord, "t"},
+		{tokenComment, "/* block */"},
+		{tokenWord, "WHERE"},
+		{tokenWord, "x"},
+		{tokenOperator, ">="},
+		{tokenNumber, "1.5e-3"},
+		{tokenWord, "AND"},
+		{tokenWord, "y"},
+		{tokenOperator, "!="},
+		{tokenNumber, "0x1F"},
+		{tokenWord, "AND"},
+		{tokenWord, "z"},
+		{tokenOperator, "="},
+		{tokenOperator, "?"},
+		{tokenWord, "AND"},
+		{tokenWord, "w"},
+		{tokenOperator, "="},
+		{tokenWord, "$1"},
+		{tokenWord, "AND"},
+		{tokenOperator, "{"},
+		{tokenWord, "p"},
+		{tokenOperator, ":"},
+		{tokenWord, "UInt8"},
+		{tokenOperator, "}"},
+		{tokenOperator, "<>"},
+		{tokenString, "'open"},
+	}, tokenize(query))
+}
+
+func TestTokenizeNumbers(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, []token{
+		{tokenNumber, "1.5e+3"},
+		{tokenNumber, "0xFF_ab"},
+		{tokenNumber, "12"},
+		{tokenWord, "db"},
+		{tokenNumber, "0x1F"},
+		{tokenWord, "g"},
+	}, tokenize("1.5e+3 0xFF_ab 12db 0x1Fg"))
+}
@@ -0,0 +1,252 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Leniency selects the differences a StructuralMatcher tolerates
+// between the structure of the expected and the actual SQL.
+type Leniency uint
+
+const (
+	// IgnoreSettingsOrder lets SETTINGS clauses list their settings in any order.
+	IgnoreSettingsOrder Leniency = 1 << iota
+	// IgnoreAliases lets aliases be named differently as long as they alias
+	// the same expressions and are referred to at the same places.
+	IgnoreAliases
+	// IgnoreConditionOrder lets the operands of AND and OR be in any order.
+	IgnoreConditionOrder
+
+	// Lenient tolerates all of the differences above.
+	Lenient = IgnoreSettingsOrder | IgnoreAliases | IgnoreConditionOrder
+)
+
+// MatchStructural is a StructuralMatcher tolerating all differences
+// known to Leniency.
+var MatchStructural = StructuralMatcher(Lenient)
+
+// StructuralMatcher returns a QueryMatcher parsing SELECT, INSERT and ALTER
+// TABLE statements into syntax trees and comparing them, so formatting,
+// comments, the case of keywords and the quoting of identifiers do not
+// matter, nor do the differences selected by leniency. Statements it is
+// unable to parse are compared with MatchNormalized.
+func StructuralMatcher(leniency Leniency) QueryMatcher {
+	return QueryMatcherFunc(func(expectedSQL, actualSQL string) error {
+		expected, err := parseQuery(expectedSQL)
+		if err != nil {
+			return MatchNormalized.Match(expectedSQL, actualSQL)
+		}
+		actual, err := parseQuery(actualSQL)
+		if err != nil {
+			return MatchNormalized.Match(expectedSQL, actualSQL)
+		}
+
+		cmp := &comparison{leniency: leniency, expected: expected, actual: actual}
+		if diff := cmp.compare(expected.root, actual.root); diff != "" {
+			return fmt.Errorf(`actual sql: "%s" does not match the structure of expected "%s": %s`,
+				actualSQL, expectedSQL, diff)
+		}
+		return nil
+	})
+}
+
+// comparison compares the syntax trees of two statements.
+type comparison struct {
+	leniency         Leniency
+	expected, actual *parsedQuery
+}
+
+func (c *comparison) lenient(l Leniency) bool {
+	return c.leniency&l != 0
+}
+
+// compare returns a description of the first difference
+// between the nodes e and a, or an empty string if there is none.
+func (c *comparison) compare(e, a *node) string {
+	if e.kind != a.kind {
+		return mismatch(e, a)
+	}
+
+	switch e.kind {
+	case nodeAlias:
+		if !c.lenient(IgnoreAliases) && e.text != a.text {
+			return mismatch(e, a)
+		}
+		return c.compare(e.children[0], a.children[0])
+	case nodeIdent:
+		if c.lenient(IgnoreAliases) {
+			return c.compareIdent(e, a)
+		}
+	case nodeCall:
+		if !sameFunction(e.text, a.text) {
+			return mismatch(e, a)
+		}
+		return c.compareChildren(e, a)
+	case nodeAnd, nodeOr:
+		if c.lenient(IgnoreConditionOrder) {
+			return c.compareUnordered(e, a)
+		}
+	case nodeSettings:
+		if c.lenient(IgnoreSettingsOrder) {
+			return c.compareSettings(e, a)
+		}
+	case nodeClause:
+		if e.text != a.text {
+			return mismatch(e, a)
+		}
+		diff := c.compareChildren(e, a)
+		if diff != "" && e.text != "" {
+			diff = e.text + ": " + diff
+		}
+		return diff
+	}
+
+	if e.text != a.text {
+		return mismatch(e, a)
+	}
+	return c.compareChildren(e, a)
+}
+
+// caseInsensitiveFunctions are the functions ClickHouse resolves regardless
+// of the case of their name, mostly SQL standard ones, keyed in lower case.
+// The names of all other functions are case-sensitive.
+var caseInsensitiveFunctions = map[string]bool{
+	"abs": true, "avg": true, "cast": true, "ceil": true, "ceiling": true,
+	"coalesce": true, "concat": true, "corr": true, "count": true, "covar_pop": true,
+	"covar_samp": true, "current_date": true, "current_timestamp": true, "date_trunc": true, "day": true,
+	"exp": true, "extract": true, "floor": true, "greatest": true, "hour": true,
+	"if": true, "ifnull": true, "lcase": true, "least": true, "left": true,
+	"length": true, "ln": true, "locate": true, "lower": true, "ltrim": true,
+	"max": true, "min": true, "minute": true, "mod": true, "month": true,
+	"now": true, "nullif": true, "position": true, "pow": true, "power": true,
+	"quarter": true, "replace": true, "right": true, "round": true, "rtrim": true,
+	"second": true, "sign": true, "sqrt": true, "stddev_pop": true, "stddev_samp": true,
+	"substr": true, "substring": true, "sum": true, "trim": true, "truncate": true,
+	"ucase": true, "upper": true, "var_pop": true, "var_samp": true, "year": true,
+}
+
+// sameFunction reports whether the function names e and a name the same
+// function, comparing the case-insensitive ones regardless of case.
+func sameFunction(e, a string) bool {
+	if e == a {
+		return true
+	}
+	return strings.EqualFold(e, a) && caseInsensitiveFunctions[strings.ToLower(e)]
+}
+
+// compareIdent compares possibly qualified identifiers, taking the
+// parts naming an alias as equal if they refer to the same alias.
+func (c *comparison) compareIdent(e, a *node) string {
+	eparts, aparts := strings.Split(e.text, "."), strings.Split(a.text, ".")
+	if len(eparts) != len(aparts) {
+		return mismatch(e, a)
+	}
+	for i := range eparts {
+		ei, eok := c.expected.aliases[eparts[i]]
+		ai, aok := c.actual.aliases[aparts[i]]
+		if eok != aok || eok && ei != ai || !eok && eparts[i] != aparts[i] {
+			return mismatch(e, a)
+		}
+	}
+	return ""
+}
+
+// compareChildren compares the children of e and a pairwise.
+func (c *comparison) compareChildren(e, a *node) string {
+	if len(e.children) != len(a.children) {
+		return c.compareLists(e, a)
+	}
+	for i := range e.children {
+		if diff := c.compare(e.children[i], a.children[i]); diff != "" {
+			return diff
+		}
+	}
+	return ""
+}
+
+// compareLists describes children of e and a differing in number,
+// in terms of the first child missing or in excess.
+func (c *comparison) compareLists(e, a *node) string {
+	for i := 0; ; i++ {
+		switch {
+		case i >= len(e.children):
+			return fmt.Sprintf("%s was not expected", a.children[i])
+		case i >= len(a.children):
+			return fmt.Sprintf("expected %s, got nothing", e.children[i])
+		}
+		if diff := c.compare(e.children[i], a.children[i]); diff != "" {
+			return diff
+		}
+	}
+}
+
+// compareUnordered compares the children of e and
+// a as multisets, so their order does not matter.
+func (c *comparison) compareUnordered(e, a *node) string {
+	if len(e.children) != len(a.children) {
+		return mismatch(e, a)
+	}
+	used := make([]bool, len(a.children))
+next:
+	for _, ec := range e.children {
+		for i, ac := range a.children {
+			if !used[i] && c.compare(ec, ac) == "" {
+				used[i] = true
+				continue next
+			}
+		}
+		return fmt.Sprintf("expected %s in %s", ec, a)
+	}
+	return ""
+}
+
+// compareSettings compares SETTINGS clauses by setting name.
+func (c *comparison) compareSettings(e, a *node) string {
+	settings := func(n *node) map[string]*node {
+		m := make(map[string]*node, len(n.children))
+		for _, s := range n.children {
+			m[s.text] = s.children[0]
+		}
+		return m
+	}
+	expected, actual := settings(e), settings(a)
+
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, ok := actual[name]
+		if !ok {
+			return fmt.Sprintf("SETTINGS: expected %s = %s, got nothing", name, expected[name])
+		}
+		if diff := c.compare(expected[name], value); diff != "" {
+			return "SETTINGS: " + name + ": " + diff
+		}
+	}
+	for _, s := range a.children {
+		if _, ok := expected[s.text]; !ok {
+			return fmt.Sprintf("SETTINGS: %s was not expected", s)
+		}
+	}
+	return ""
+}
+
+func mismatch(e, a *node) string {
+	return fmt.Sprintf("expected %s, got %s", e, a)
+}
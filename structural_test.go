@@ -0,0 +1,229 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchStructural(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name             string
+		expected, actual string
+		match            bool
+	}{
+		{
+			name:     "formatting and keyword case",
+			expected: "SELECT id, title FROM articles WHERE status = 'draft'",
+			actual:   "select id,\n  title\nfrom `articles` -- drafts only\nwhere status = 'draft'",
+			match:    true,
+		},
+		{
+			name:     "settings order",
+			expected: "SELECT id FROM articles SETTINGS max_threads = 2, max_memory_usage = 1000",
+			actual:   "SELECT id FROM articles SETTINGS max_memory_usage = 1000, max_threads = 2",
+			match:    true,
+		},
+		{
+			name:     "setting value",
+			expected: "SELECT id FROM articles SETTINGS max_threads = 2",
+			actual:   "SELECT id FROM articles SETTINGS max_threads = 4",
+		},
+		{
+			name:     "alias names",
+			expected: "SELECT count() AS total FROM articles AS a WHERE a.id > 1 ORDER BY total DESC",
+			actual:   "SELECT count() AS cnt FROM articles AS art WHERE art.id > 1 ORDER BY cnt DESC",
+			match:    true,
+		},
+		{
+			name:     "renamed alias",
+			expected: "SELECT count() AS total, sum(views) AS views_sum FROM articles ORDER BY total",
+			actual:   "SELECT count() AS n, sum(views) AS v FROM articles ORDER BY n",
+			match:    true,
+		},
+		{
+			name:     "alias referred to at another place",
+			expected: "SELECT count() AS total, sum(views) AS views_sum FROM articles ORDER BY total",
+			actual:   "SELECT count() AS n, sum(views) AS v FROM articles ORDER BY v",
+		},
+		{
+			name:     "commutative AND and OR",
+			expected: "SELECT id FROM articles WHERE status = 'draft' AND (author = ? OR editor = ?) AND id > 10",
+			actual:   "SELECT id FROM articles WHERE id > 10 AND (editor = ? OR author = ?) AND status = 'draft'",
+			match:    true,
+		},
+		{
+			name:     "AND operands",
+			expected: "SELECT id FROM articles WHERE status = 'draft' AND id > 10",
+			actual:   "SELECT id FROM articles WHERE status = 'draft' AND id > 11",
+		},
+		{
+			name:     "precedence",
+			expected: "SELECT id FROM articles WHERE a = 1 AND b = 2 OR c = 3",
+			actual:   "SELECT id FROM articles WHERE a = 1 AND (b = 2 OR c = 3)",
+		},
+		{
+			name:     "redundant parentheses",
+			expected: "SELECT id FROM articles WHERE (a = 1) AND ((b + 1) * 2 = 4)",
+			actual:   "SELECT id FROM articles WHERE a = 1 AND (b + 1) * 2 = 4",
+			match:    true,
+		},
+		{
+			name:     "select list order",
+			expected: "SELECT id, title FROM articles",
+			actual:   "SELECT title, id FROM articles",
+		},
+		{
+			name:     "function name case",
+			expected: "SELECT count(DISTINCT id) FROM articles",
+			actual:   "SELECT COUNT(DISTINCT id) FROM articles",
+			match:    true,
+		},
+		{
+			name:     "case-sensitive function name",
+			expected: "SELECT toDate(created_at) FROM articles",
+			actual:   "SELECT todate(created_at) FROM articles",
+		},
+		{
+			name:     "identifier case",
+			expected: "SELECT id FROM articles",
+			actual:   "SELECT ID FROM articles",
+		},
+		{
+			name:     "joins, subqueries and unions",
+			expected: "SELECT a.id FROM articles AS a LEFT JOIN users AS u ON u.id = a.author_id AND u.active WHERE a.id IN (SELECT id FROM featured) UNION ALL SELECT id FROM drafts",
+			actual:   "SELECT x.id FROM articles x LEFT JOIN users y ON y.active AND y.id = x.author_id WHERE x.id IN (SELECT id FROM featured) UNION ALL SELECT id FROM drafts",
+			match:    true,
+		},
+		{
+			name:     "join kind",
+			expected: "SELECT id FROM articles LEFT JOIN users USING (id)",
+			actual:   "SELECT id FROM articles INNER JOIN users USING (id)",
+		},
+		{
+			name:     "insert",
+			expected: "INSERT INTO articles (id, title) SETTINGS async_insert = 1, wait_for_async_insert = 0",
+			actual:   "insert into `articles` (`id`, `title`) settings wait_for_async_insert = 0, async_insert = 1",
+			match:    true,
+		},
+		{
+			name:     "batch insert",
+			expected: "INSERT INTO articles (id, title) SETTINGS async_insert = 1, wait_for_async_insert = 0 VALUES",
+			actual:   "insert into articles (`id`, `title`) settings wait_for_async_insert = 0, async_insert = 1 values",
+			match:    true,
+		},
+		{
+			name:     "insert columns",
+			expected: "INSERT INTO articles (id, title)",
+			actual:   "INSERT INTO articles (id)",
+		},
+		{
+			name:     "alter",
+			expected: "ALTER TABLE articles UPDATE title = ?, views = views + 1 WHERE id = ? AND status = 'draft'",
+			actual:   "alter table articles update title = ?, views = views + 1 where status = 'draft' and id = ?",
+			match:    true,
+		},
+		{
+			name:     "alter delete",
+			expected: "ALTER TABLE articles DELETE WHERE id = ?",
+			actual:   "ALTER TABLE articles DELETE WHERE id != ?",
+		},
+		{
+			name:     "unsupported statements are compared normalized",
+			expected: "TRUNCATE TABLE articles",
+			actual:   "truncate table articles",
+			match:    true,
+		},
+	} {
+		err := MatchStructural.Match(tc.expected, tc.actual)
+		if tc.match {
+			assert.NoError(t, err, tc.name)
+		} else {
+			assert.Error(t, err, tc.name)
+		}
+	}
+}
+
+func TestStructuralMatcherLeniency(t *testing.T) {
+	t.Parallel()
+	settings := [2]string{
+		"SELECT id FROM articles SETTINGS max_threads = 2, readonly = 1",
+		"SELECT id FROM articles SETTINGS readonly = 1, max_threads = 2",
+	}
+	aliases := [2]string{
+		"SELECT count() AS total FROM articles ORDER BY total",
+		"SELECT count() AS n FROM articles ORDER BY n",
+	}
+	conditions := [2]string{
+		"SELECT id FROM articles WHERE a = 1 AND b = 2",
+		"SELECT id FROM articles WHERE b = 2 AND a = 1",
+	}
+
+	for _, tc := range []struct {
+		leniency                      Leniency
+		settings, aliases, conditions bool
+	}{
+		{0, false, false, false},
+		{IgnoreSettingsOrder, true, false, false},
+		{IgnoreAliases, false, true, false},
+		{IgnoreConditionOrder, false, false, true},
+		{Lenient, true, true, true},
+	} {
+		matcher := StructuralMatcher(tc.leniency)
+		assert.Equal(t, tc.settings, matcher.Match(settings[0], settings[1]) == nil, "settings with leniency %b", tc.leniency)
+		assert.Equal(t, tc.aliases, matcher.Match(aliases[0], aliases[1]) == nil, "aliases with leniency %b", tc.leniency)
+		assert.Equal(t, tc.conditions, matcher.Match(conditions[0], conditions[1]) == nil, "conditions with leniency %b", tc.leniency)
+		assert.NoError(t, matcher.Match(conditions[0], conditions[0]))
+	}
+}
+
+func TestMatchStructuralError(t *testing.T) {
+	t.Parallel()
+	err := MatchStructural.Match(
+		"SELECT id FROM articles WHERE status = 'draft' SETTINGS max_threads = 2",
+		"SELECT id FROM articles WHERE status = 'done' SETTINGS max_threads = 2",
+	)
+	assert.EqualError(t, err, `actual sql: "SELECT id FROM articles WHERE status = 'done' SETTINGS max_threads = 2" `+
+		`does not match the structure of expected "SELECT id FROM articles WHERE status = 'draft' SETTINGS max_threads = 2": `+
+		`WHERE: expected 'draft', got 'done'`)
+
+	err = MatchStructural.Match("SELECT id FROM articles SETTINGS max_threads = 2", "SELECT id FROM articles SETTINGS max_threads = 2, readonly = 1")
+	assert.ErrorContains(t, err, "SETTINGS: readonly = 1 was not expected")
+
+	err = MatchStructural.Match("SELECT id, title FROM articles", "SELECT id FROM articles")
+	assert.ErrorContains(t, err, "SELECT: expected title, got nothing")
+}
+
+func TestMockWithMatchStructural(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseWithQueryMatcher(nil, MatchStructural)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery("SELECT id FROM articles WHERE status = ? AND author = ? SETTINGS max_threads = 2, readonly = 1").WithArgs("draft", "jane")
+	mock.ExpectExec("TRUNCATE TABLE articles").WithMatcher(MatchEqual)
+
+	_, err = mock.Query(context.Background(), `
+		SELECT id
+		FROM articles
+		WHERE author = ? AND status = ?
+		SETTINGS readonly = 1, max_threads = 2`, "draft", "jane")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.Exec(context.Background(), "TRUNCATE TABLE articles"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
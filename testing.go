@@ -0,0 +1,78 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// NewClickHouseNativeT creates clickhousemock database mock bound to t.
+// Expectations are verified with ExpectationsWereMet when the test ends,
+// and calls which were not expected fail the test with their call site
+// instead of only returning an error or, for Stats and Contributors,
+// panicking.
+func NewClickHouseNativeT(t testing.TB, options *clickhouse.Options) *clickhousemock {
+	t.Helper()
+	mock, err := NewClickHouseNative(options)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.t = t
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+	return mock
+}
+
+// unexpected reports a call which did not match any expectation to the
+// test the mock is bound to. It returns false if the mock is not bound
+// to a test, in which case the caller has to surface err itself.
+func (c *clickhousemock) unexpected(err error) bool {
+	if c.t == nil {
+		return false
+	}
+	c.t.Helper()
+	if site := callSite(); site != "" {
+		c.t.Errorf("%s: %s", site, err)
+	} else {
+		c.t.Errorf("%s", err)
+	}
+	return true
+}
+
+var packagePath = reflect.TypeOf(clickhousemock{}).PkgPath()
+
+// callSite returns the file and line of the first caller
+// outside of the mock, which is the code under test.
+func callSite() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		inMock := strings.HasPrefix(frame.Function, packagePath+".") && !strings.HasSuffix(frame.File, "_test.go")
+		if !inMock && frame.File != "" {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingTB records the failures and cleanups
+// registered by the mock instead of acting on them.
+type recordingTB struct {
+	testing.TB
+	mu       sync.Mutex
+	errors   []string
+	cleanups []func()
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+}
+
+func (r *recordingTB) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *recordingTB) cleanup() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+func TestNewClickHouseNativeT(t *testing.T) {
+	t.Parallel()
+	mock := NewClickHouseNativeT(t, nil)
+
+	mock.ExpectExec("TRUNCATE TABLE articles")
+	assert.NoError(t, mock.Exec(context.Background(), "TRUNCATE TABLE articles"))
+}
+
+func TestNewClickHouseNativeTVerifiesOnCleanup(t *testing.T) {
+	t.Parallel()
+	tb := &recordingTB{TB: t}
+	mock := NewClickHouseNativeT(tb, nil)
+
+	mock.ExpectExec("TRUNCATE TABLE articles")
+	tb.cleanup()
+
+	if assert.Len(t, tb.errors, 1) {
+		assert.Contains(t, tb.errors[0], "there were unfulfilled expectations")
+	}
+}
+
+func TestNewClickHouseNativeTReportsUnexpectedCalls(t *testing.T) {
+	t.Parallel()
+	tb := &recordingTB{TB: t}
+	mock := NewClickHouseNativeT(tb, nil)
+
+	err := mock.Exec(context.Background(), "DROP TABLE articles")
+	assert.Error(t, err)
+
+	assert.NotPanics(t, func() {
+		assert.Equal(t, []string{}, mock.Contributors())
+		mock.Stats()
+	})
+
+	if assert.Len(t, tb.errors, 3) {
+		assert.Regexp(t, `^.*testing_test\.go:\d+: all expectations were already fulfilled, call to Exec statement with query 'DROP TABLE articles' was not expected$`, tb.errors[0])
+		assert.Contains(t, tb.errors[1], "call to database Contributors was not expected")
+		assert.Contains(t, tb.errors[2], "call to database Stats was not expected")
+	}
+}
@@ -0,0 +1,67 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnmetExpectation describes a single expectation
+// reported by ExpectationsWereMet.
+type UnmetExpectation struct {
+	// Method is the driver.Conn or driver.Batch method the expectation is for.
+	Method string
+	// Batch is the query of the prepared batch a batch step, e.g. ExpectSend,
+	// belongs to. It is empty for driver.Conn expectations.
+	Batch string
+	// Calls is the number of times the expectation was matched.
+	Calls int
+	// Reason tells why the expectation is not met.
+	Reason string
+	// Expectation is the string representation of the expectation.
+	Expectation string
+}
+
+// newUnmetExpectation has to be called with e locked.
+func newUnmetExpectation(e expectation, batch, reason string) UnmetExpectation {
+	return UnmetExpectation{
+		Method:      e.method(),
+		Batch:       batch,
+		Calls:       e.common().calls,
+		Reason:      reason,
+		Expectation: e.String(),
+	}
+}
+
+func (u UnmetExpectation) String() string {
+	return fmt.Sprintf("%s: %s", u.Reason, u.Expectation)
+}
+
+// UnmetExpectationsError is returned by ExpectationsWereMet
+// and lists every expectation which was not met.
+type UnmetExpectationsError struct {
+	Unmet []UnmetExpectation
+}
+
+func (e *UnmetExpectationsError) Error() string {
+	if len(e.Unmet) == 1 {
+		return fmt.Sprintf("there is a remaining expectation which was not met: %s", e.Unmet[0])
+	}
+	msg := fmt.Sprintf("there are %d remaining expectations which were not met:", len(e.Unmet))
+	var lines []string
+	for _, u := range e.Unmet {
+		lines = append(lines, strings.ReplaceAll(u.String(), "\n", "\n    "))
+	}
+	return msg + "\n  - " + strings.Join(lines, "\n  - ")
+}
@@ -0,0 +1,185 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockhouse
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpectationsWereMetReportsEveryUnmetExpectation(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	prep := mock.ExpectPrepareBatch("INSERT INTO articles")
+	prep.ExpectAppend()
+	prep.ExpectFlush()
+	prep.ExpectSend()
+	prep.ExpectClose()
+	mock.ExpectExec("OPTIMIZE TABLE articles FINAL")
+	mock.ExpectQuery("SELECT count() FROM articles").RowsWillBeClosed()
+
+	batch, err := mock.PrepareBatch(context.Background(), "INSERT INTO articles")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, batch.Append(1))
+	assert.NoError(t, batch.Close())
+
+	err = mock.ExpectationsWereMet()
+	var unmetErr *UnmetExpectationsError
+	if !assert.True(t, errors.As(err, &unmetErr)) {
+		return
+	}
+
+	var got [][2]string
+	for _, u := range unmetErr.Unmet {
+		got = append(got, [2]string{u.Method, u.Batch})
+	}
+	assert.Equal(t, [][2]string{
+		{"Flush", "INSERT INTO articles"},
+		{"Send", "INSERT INTO articles"},
+		{"Exec", ""},
+		{"Query", ""},
+	}, got)
+	assert.Contains(t, err.Error(), "there are 4 remaining expectations which were not met:")
+	assert.Contains(t, err.Error(), "batch step not matched: Flush(INSERT INTO articles)")
+}
+
+func TestExpectationsWereMetChecksQueryRowsClosed(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery("SELECT count() FROM articles").RowsWillBeClosed()
+
+	rows, err := mock.Query(context.Background(), "SELECT count() FROM articles")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	err = mock.ExpectationsWereMet()
+	var unmetErr *UnmetExpectationsError
+	if assert.True(t, errors.As(err, &unmetErr)) && assert.Len(t, unmetErr.Unmet, 1) {
+		assert.Equal(t, "1 of 1 returned rows were not closed", unmetErr.Unmet[0].Reason)
+		assert.Equal(t, 1, unmetErr.Unmet[0].Calls)
+	}
+
+	assert.NoError(t, rows.Close())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpectationsWereMetChecksRowsOfEveryQueryClosed(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	rows := NewRows([]ColumnType{{Name: "count()", Type: "UInt64"}}, [][]any{{uint64(1)}})
+	mock.ExpectQuery("SELECT count() FROM articles").WillReturnRows(rows).RowsWillBeClosed()
+	mock.ExpectQuery("SELECT count() FROM comments").WillReturnRows(rows).RowsWillBeClosed()
+
+	articles, err := mock.Query(context.Background(), "SELECT count() FROM articles")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = mock.Query(context.Background(), "SELECT count() FROM comments")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, articles.Close())
+
+	err = mock.ExpectationsWereMet()
+	var unmetErr *UnmetExpectationsError
+	if assert.True(t, errors.As(err, &unmetErr)) && assert.Len(t, unmetErr.Unmet, 1) {
+		assert.Equal(t, "1 of 1 returned rows were not closed", unmetErr.Unmet[0].Reason)
+		assert.Contains(t, unmetErr.Unmet[0].Expectation, "SELECT count() FROM comments")
+	}
+}
+
+func TestExpectationsWereMetCountsClosedRowsOfRepeatedQuery(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery("SELECT count() FROM articles").Times(2).RowsWillBeClosed()
+
+	first, err := mock.Query(context.Background(), "SELECT count() FROM articles")
+	if !assert.NoError(t, err) {
+		return
+	}
+	second, err := mock.Query(context.Background(), "SELECT count() FROM articles")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, first.Close())
+	assert.NoError(t, first.Close())
+
+	err = mock.ExpectationsWereMet()
+	var unmetErr *UnmetExpectationsError
+	if assert.True(t, errors.As(err, &unmetErr)) && assert.Len(t, unmetErr.Unmet, 1) {
+		assert.Equal(t, "1 of 2 returned rows were not closed", unmetErr.Unmet[0].Reason)
+	}
+
+	assert.NoError(t, second.Close())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpectationsWereMetSkipsStepsOfUnpreparedBatch(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	prep := mock.ExpectPrepareBatch("INSERT INTO articles")
+	prep.ExpectAppend()
+	prep.ExpectSend()
+
+	err = mock.ExpectationsWereMet()
+	var unmetErr *UnmetExpectationsError
+	if assert.True(t, errors.As(err, &unmetErr)) && assert.Len(t, unmetErr.Unmet, 1) {
+		assert.Equal(t, "PrepareBatch", unmetErr.Unmet[0].Method)
+		assert.Equal(t, "not matched", unmetErr.Unmet[0].Reason)
+	}
+}
+
+func TestExpectationsWereMetChecksBatchSent(t *testing.T) {
+	t.Parallel()
+	mock, err := NewClickHouseNative(nil)
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectPrepareBatch("INSERT INTO articles").WillBeSent()
+
+	batch, err := mock.PrepareBatch(context.Background(), "INSERT INTO articles")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.EqualError(t, mock.ExpectationsWereMet(), "there is a remaining expectation which was not met: batch was not sent: "+mock.expected[0].String())
+
+	assert.NoError(t, batch.Send())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}